@@ -0,0 +1,209 @@
+// Package auth checks the API keys of requests against the keys in the authConfig of vqlite.yaml,
+// it is used by the HTTP middleware and the gRPC interceptor alike.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"vqlite/config"
+	"vqlite/core"
+	vqerrors "vqlite/errors"
+)
+
+// Role grants the operations of the roles below it, a writer may do everything a reader may.
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleReader
+	RoleWriter
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{
+	RoleNone:   "none",
+	RoleReader: "reader",
+	RoleWriter: "writer",
+	RoleAdmin:  "admin",
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+func ParseRole(name string) (Role, error) {
+	for role, roleName := range roleNames {
+		if role != RoleNone && roleName == name {
+			return role, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("invalid role [%s], must be one of admin, writer, reader", name)
+}
+
+// Key is an API key of the config, a key with Collections may only access these collections.
+type Key struct {
+	Name        string
+	Role        Role
+	Collections map[string]struct{}
+}
+
+// Scoped reports whether the key is limited to some collections.
+func (k *Key) Scoped() bool {
+	return len(k.Collections) > 0
+}
+
+// Authorize checks that the key has role and may access collectionName.
+// An empty collectionName is an operation on no single collection, e.g. statistics or aliases,
+// scoped keys may only read those, and only see their collections in them, see Allows.
+func (k *Key) Authorize(role Role, collectionName string) error {
+	if k.Role < role {
+		return vqerrors.PermissionDenied("key [%s] with role %s is not allowed, %s required", k.Name, k.Role, role)
+	}
+	if !k.Scoped() {
+		return nil
+	}
+	if collectionName == "" {
+		if role > RoleReader {
+			return vqerrors.PermissionDenied("key [%s] is scoped to collections", k.Name)
+		}
+		return nil
+	}
+	if !k.allowsCollection(collectionName) {
+		return vqerrors.PermissionDenied("key [%s] is not allowed to access collection [%s]", k.Name, collectionName)
+	}
+	return nil
+}
+
+// Allows reports whether the key may see collectionName in statistics and alias listings.
+// A nil key, as when auth is disabled, and a key not scoped to collections see every collection.
+func (k *Key) Allows(collectionName string) bool {
+	if k == nil || !k.Scoped() {
+		return true
+	}
+	return k.allowsCollection(collectionName)
+}
+
+// allowsCollection allows the collections of the key and the collections its aliases point to.
+func (k *Key) allowsCollection(collectionName string) bool {
+	if _, ok := k.Collections[collectionName]; ok {
+		return true
+	}
+	for name := range k.Collections {
+		if core.VqliteCollectionList.Resolve(name) == collectionName {
+			return true
+		}
+	}
+	return false
+}
+
+// keys and debugKeys are indexed by the sha256 of the key, so lookups do not compare the secret itself.
+var (
+	enabled   bool
+	keys      map[[sha256.Size]byte]*Key
+	debugKeys map[[sha256.Size]byte]struct{}
+)
+
+// Init loads the keys of the config, it must be called before serving requests.
+func Init() error {
+	authConfig := config.GlobalConfig.AuthConfig
+	enabled = authConfig.Enabled
+	keys = make(map[[sha256.Size]byte]*Key, len(authConfig.Keys))
+	debugKeys = make(map[[sha256.Size]byte]struct{}, len(authConfig.DebugKeys))
+	if !enabled {
+		return nil
+	}
+	if len(authConfig.Keys) == 0 {
+		return fmt.Errorf("auth is enabled without keys")
+	}
+	for i, keyConfig := range authConfig.Keys {
+		name := keyConfig.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		if keyConfig.Key == "" {
+			return fmt.Errorf("key [%s] is empty", name)
+		}
+		role, err := ParseRole(keyConfig.Role)
+		if err != nil {
+			return fmt.Errorf("key [%s]: %w", name, err)
+		}
+		hash := sha256.Sum256([]byte(keyConfig.Key))
+		if _, ok := keys[hash]; ok {
+			return fmt.Errorf("key [%s] is duplicated", name)
+		}
+		key := &Key{Name: name, Role: role, Collections: make(map[string]struct{}, len(keyConfig.Collections))}
+		for _, collectionName := range keyConfig.Collections {
+			key.Collections[collectionName] = struct{}{}
+		}
+		keys[hash] = key
+	}
+	for _, debugKey := range authConfig.DebugKeys {
+		if debugKey == "" {
+			return fmt.Errorf("debug key is empty")
+		}
+		debugKeys[sha256.Sum256([]byte(debugKey))] = struct{}{}
+	}
+	return nil
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying the key of the request.
+func NewContext(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the key of the request, nil when auth is disabled.
+func FromContext(ctx context.Context) *Key {
+	key, _ := ctx.Value(contextKey{}).(*Key)
+	return key
+}
+
+// Enabled reports whether requests need a key.
+func Enabled() bool {
+	return enabled
+}
+
+// Authenticate returns the key of the config matching token.
+func Authenticate(token string) (*Key, error) {
+	if token == "" {
+		return nil, vqerrors.Unauthenticated("missing API key")
+	}
+	key, ok := keys[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil, vqerrors.Unauthenticated("invalid API key")
+	}
+	return key, nil
+}
+
+// AuthenticateDebug checks token for /metrics and /debug/pprof, these take the debug keys
+// and the admin keys not scoped to collections.
+func AuthenticateDebug(token string) error {
+	if token == "" {
+		return vqerrors.Unauthenticated("missing API key")
+	}
+	if _, ok := debugKeys[sha256.Sum256([]byte(token))]; ok {
+		return nil
+	}
+	key, err := Authenticate(token)
+	if err != nil {
+		return err
+	}
+	if key.Role < RoleAdmin || key.Scoped() {
+		return vqerrors.PermissionDenied("key [%s] is not allowed to access debug endpoints", key.Name)
+	}
+	return nil
+}
+
+// Token returns the key of an `Authorization: Bearer <key>` or `X-API-Key: <key>` header.
+func Token(authorization string, apiKey string) string {
+	if apiKey != "" {
+		return apiKey
+	}
+	if len(authorization) > len("Bearer ") && strings.EqualFold(authorization[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(authorization[len("Bearer "):])
+	}
+	return ""
+}
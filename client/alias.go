@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"vqlite/core"
+)
+
+// ListAliases returns all aliases with the collection they point to.
+func (c *Client) ListAliases(ctx context.Context) (map[string]string, error) {
+	aliases := make(map[string]string)
+	if _, err := c.doJSON(ctx, http.MethodGet, "/api/alias", nil, &aliases); err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// GetAlias returns the collection the alias points to.
+func (c *Client) GetAlias(ctx context.Context, alias string) (string, error) {
+	var data struct {
+		Collection string `json:"collection"`
+	}
+	if _, err := c.doJSON(ctx, http.MethodGet, "/api/alias/"+url.PathEscape(alias), nil, &data); err != nil {
+		return "", err
+	}
+	return data.Collection, nil
+}
+
+// SetAlias points the alias to the collection, an existing alias is moved.
+func (c *Client) SetAlias(ctx context.Context, alias string, collectionName string) error {
+	req := &core.SetAliasRequest{Collection: collectionName}
+	_, err := c.doJSON(ctx, http.MethodPut, "/api/alias/"+url.PathEscape(alias), req, nil)
+	return err
+}
+
+func (c *Client) DeleteAlias(ctx context.Context, alias string) error {
+	_, err := c.doJSON(ctx, http.MethodDelete, "/api/alias/"+url.PathEscape(alias), nil, nil)
+	return err
+}
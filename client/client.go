@@ -0,0 +1,309 @@
+// Package client is the Go SDK for the VQLite HTTP API, it uses the request and response types of core.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"vqlite/core"
+	vqerrors "vqlite/errors"
+)
+
+const (
+	defaultMaxRetries   = 3
+	defaultMinRetryWait = 200 * time.Millisecond
+	defaultMaxRetryWait = 5 * time.Second
+)
+
+// Client calls the VQLite HTTP API, it is safe for concurrent use.
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	maxRetries   int
+	minRetryWait time.Duration
+	maxRetryWait time.Duration
+	token        string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient uses httpClient instead of the default pooled client.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout sets the timeout of every HTTP request, a timeout in ctx is applied as well.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// WithRetry retries failed requests maxRetries times, waiting from minWait doubling up to maxWait.
+// A maxRetries of 0 disables retries.
+func WithRetry(maxRetries int, minWait time.Duration, maxWait time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.minRetryWait = minWait
+		c.maxRetryWait = maxWait
+	}
+}
+
+// WithTLSConfig sets the TLS config of the default pooled client, e.g. the CA of the server
+// and the client certificate for mutual TLS. The base url must be https.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *Client) {
+		if transport, ok := c.httpClient.Transport.(*http.Transport); ok {
+			transport.TLSClientConfig = tlsConfig
+		}
+	}
+}
+
+// WithToken sends token as the API key of every request, for servers with auth enabled.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New returns a client for the VQLite server at baseURL, e.g. http://127.0.0.1:8880.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid VQLite url [%s]", baseURL)
+	}
+	c := &Client{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		httpClient:   &http.Client{Transport: newTransport()},
+		maxRetries:   defaultMaxRetries,
+		minRetryWait: defaultMinRetryWait,
+		maxRetryWait: defaultMaxRetryWait,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// newTransport keeps idle connections to the server, so requests reuse them.
+func newTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 32,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+// Error is returned for requests the server answered with an error.
+type Error struct {
+	StatusCode int
+	Code       vqerrors.Code
+	Message    string
+	EngineCode string
+	// State and RetryAfter are set for collections which are not loaded yet
+	State      string
+	RetryAfter int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("vqlite error %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *Error) ErrorCode() vqerrors.Code {
+	return e.Code
+}
+
+// response is the body of every JSON response.
+type response struct {
+	Status         string               `json:"status"`
+	Data           json.RawMessage      `json:"data"`
+	Count          int                  `json:"count"`
+	Partial        bool                 `json:"partial"`
+	FailedSegments []core.FailedSegment `json:"failed_segments"`
+	Debug          *core.SearchDebug    `json:"debug"`
+	Code           vqerrors.Code        `json:"code"`
+	Error          string               `json:"error"`
+	EngineCode     string               `json:"engine_code"`
+	State          string               `json:"state"`
+}
+
+// doJSON sends body as JSON and decodes the data of the response into out, if not nil.
+func (c *Client) doJSON(ctx context.Context, method string, path string, body interface{}, out interface{}) (*response, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := c.do(ctx, method, path, data)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	res := &response{}
+	if err := decodeResponse(resp.Body, res, out); err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return res, nil
+}
+
+// decodeResponse decodes the response body into res and its data into out, if not nil.
+func decodeResponse(body io.Reader, res *response, out interface{}) error {
+	if err := json.NewDecoder(body).Decode(res); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if out != nil && len(res.Data) > 0 {
+		if err := json.Unmarshal(res.Data, out); err != nil {
+			return fmt.Errorf("decode response data: %w", err)
+		}
+	}
+	return nil
+}
+
+// do sends the request and retries it with backoff on 5xx and connection errors.
+// The response body must be closed by the caller, responses with an error status are returned as *Error.
+func (c *Client) do(ctx context.Context, method string, path string, body []byte) (*http.Response, error) {
+	wait := c.minRetryWait
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, bytes.NewReader(body), body != nil)
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+		var retryAfter time.Duration
+		if err == nil {
+			err = readError(resp)
+			if apiErr, ok := err.(*Error); ok {
+				retryAfter = time.Duration(apiErr.RetryAfter) * time.Second
+			}
+		}
+		if attempt >= c.maxRetries || !shouldRetry(method, err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		sleep := wait
+		if retryAfter > sleep {
+			sleep = retryAfter
+		}
+		if sleep > c.maxRetryWait {
+			sleep = c.maxRetryWait
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(sleep):
+		}
+		wait *= 2
+	}
+}
+
+func (c *Client) send(ctx context.Context, method string, path string, body io.Reader, isJSON bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if isJSON {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return c.httpClient.Do(req)
+}
+
+// shouldRetry retries connection errors, rate limited requests and 5xx responses. A 500 of a POST
+// is not retried, the request may have been applied already, e.g. documents added before the index failed.
+// For the same reason a POST is only retried on connection errors which show it was never sent.
+func shouldRetry(method string, err error) bool {
+	apiErr, ok := err.(*Error)
+	if !ok {
+		// a timeout or reset may come after the server got the request
+		var opErr *net.OpError
+		return method != http.MethodPost || (errors.As(err, &opErr) && opErr.Op == "dial")
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return apiErr.StatusCode >= http.StatusInternalServerError && method != http.MethodPost
+}
+
+// readError reads the error body of resp and closes it.
+func readError(resp *http.Response) error {
+	defer resp.Body.Close()
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	res := &response{}
+	data, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(data, res); err == nil && res.Error != "" {
+		apiErr.Code = res.Code
+		apiErr.Message = res.Error
+		apiErr.EngineCode = res.EngineCode
+		apiErr.State = res.State
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+		if apiErr.Message == "" {
+			apiErr.Message = resp.Status
+		}
+	}
+	if apiErr.Code == "" {
+		apiErr.Code = codeOfStatus(resp.StatusCode)
+	}
+	if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = retryAfter
+	}
+	return apiErr
+}
+
+// codeOfStatus guesses the error code of a response without one, e.g. from a proxy.
+func codeOfStatus(statusCode int) vqerrors.Code {
+	switch statusCode {
+	case http.StatusNotFound:
+		return vqerrors.CodeNotFound
+	case http.StatusConflict:
+		return vqerrors.CodeAlreadyExists
+	case http.StatusBadRequest:
+		return vqerrors.CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return vqerrors.CodeUnauthenticated
+	case http.StatusForbidden:
+		return vqerrors.CodePermissionDenied
+	case http.StatusServiceUnavailable:
+		return vqerrors.CodeNotReady
+	case http.StatusTooManyRequests:
+		return vqerrors.CodeRateLimited
+	case http.StatusRequestEntityTooLarge:
+		return vqerrors.CodeTooLarge
+	case http.StatusGatewayTimeout:
+		return vqerrors.CodeDeadlineExceeded
+	}
+	return vqerrors.CodeInternal
+}
+
+func collectionPath(collectionName string, elem ...string) string {
+	path := "/api/collection/" + url.PathEscape(collectionName)
+	for _, e := range elem {
+		path += "/" + e
+	}
+	return path
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"vqlite/core"
+	scann "vqlite/engine/go-scann"
+)
+
+// Ping checks that the server is up.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.doJSON(ctx, http.MethodGet, "/api/ping", nil, nil)
+	return err
+}
+
+// Statistics returns the statistics of all collections.
+func (c *Client) Statistics(ctx context.Context) (*core.VQLiteStatistics, error) {
+	stat := &core.VQLiteStatistics{}
+	if _, err := c.doJSON(ctx, http.MethodGet, "/api/stat", nil, stat); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
+
+// GetCollection returns the statistics and load state of the collection without loading it.
+func (c *Client) GetCollection(ctx context.Context, collectionName string) (*core.CollectionStatistics, error) {
+	stat := &core.CollectionStatistics{}
+	if _, err := c.doJSON(ctx, http.MethodGet, collectionPath(collectionName), nil, stat); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
+
+func (c *Client) CreateCollection(ctx context.Context, collectionName string, dim int) (*core.CollectionStatistics, error) {
+	stat := &core.CollectionStatistics{}
+	req := &core.CreateCollectionRequest{Name: collectionName, Dim: dim}
+	if _, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName), req, stat); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
+
+// CreateCollectionWithRequest creates a collection with the storage and index type of req.
+func (c *Client) CreateCollectionWithRequest(ctx context.Context, req *core.CreateCollectionRequest) (*core.CollectionStatistics, error) {
+	stat := &core.CollectionStatistics{}
+	if _, err := c.doJSON(ctx, http.MethodPost, collectionPath(req.Name), req, stat); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
+
+// CreateMemoryCollection creates a collection which never touches disk, it is dropped by the server
+// once not accessed for ttl, 0 keeps it until dropped.
+func (c *Client) CreateMemoryCollection(ctx context.Context, collectionName string, dim int, ttl time.Duration) (*core.CollectionStatistics, error) {
+	req := &core.CreateCollectionRequest{Name: collectionName, Dim: dim, Storage: scann.StorageMemory, TTL: int(ttl / time.Second)}
+	return c.CreateCollectionWithRequest(ctx, req)
+}
+
+func (c *Client) DropCollection(ctx context.Context, collectionName string) error {
+	_, err := c.doJSON(ctx, http.MethodDelete, collectionPath(collectionName), nil, nil)
+	return err
+}
+
+func (c *Client) RenameCollection(ctx context.Context, collectionName string, newName string) (*core.CollectionStatistics, error) {
+	stat := &core.CollectionStatistics{}
+	req := &core.RenameCollectionRequest{Name: newName}
+	if _, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "rename"), req, stat); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
+
+func (c *Client) CloneCollection(ctx context.Context, collectionName string, newName string) (*core.CollectionStatistics, error) {
+	stat := &core.CollectionStatistics{}
+	req := &core.CloneCollectionRequest{Name: newName}
+	if _, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "clone"), req, stat); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
+
+// Search returns the results of every query vector in order.
+func (c *Client) Search(ctx context.Context, collectionName string, vectors [][]float32, opt core.QueryOpt) ([][]core.SearchResult, error) {
+	resp, err := c.SearchResponse(ctx, collectionName, vectors, opt)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// SearchResponse returns the results of every query vector in order, flagged as partial with the
+// failed segments when opt.AllowPartial is set and some segments failed or did not answer in time,
+// and with the debug of the search when opt.Debug is set.
+func (c *Client) SearchResponse(ctx context.Context, collectionName string, vectors [][]float32, opt core.QueryOpt) (*core.SearchResponse, error) {
+	searchResp := &core.SearchResponse{}
+	req := &core.SearchRequest{Vectors: vectors, Opt: opt}
+	res, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "search"), req, &searchResp.Results)
+	if err != nil {
+		return nil, err
+	}
+	searchResp.Partial = res.Partial
+	searchResp.FailedSegments = res.FailedSegments
+	searchResp.Debug = res.Debug
+	return searchResp, nil
+}
+
+// TrainCollection trains the index of every segment with new vectors, it returns when the training is done.
+func (c *Client) TrainCollection(ctx context.Context, collectionName string, req *core.TrainRequest) error {
+	if req == nil {
+		req = &core.TrainRequest{}
+	}
+	_, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "train"), req, nil)
+	return err
+}
+
+// SetTrainOpt stores opt as the parameters of trainings of the collection which leave them unset.
+func (c *Client) SetTrainOpt(ctx context.Context, collectionName string, opt core.TrainOpt) (*core.CollectionStatistics, error) {
+	stat := &core.CollectionStatistics{}
+	if _, err := c.doJSON(ctx, http.MethodPut, collectionPath(collectionName, "train_opt"), opt, stat); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
+
+// EvalCollection reports the recall@topk and latency of the search for every pair of req.NProbes and req.Reorders.
+func (c *Client) EvalCollection(ctx context.Context, collectionName string, req *core.EvalRequest) (*core.EvalResponse, error) {
+	if req == nil {
+		req = &core.EvalRequest{}
+	}
+	result := &core.EvalResponse{}
+	if _, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "eval"), req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetSearchOpt stores opt as the options of searches of the collection which leave them unset.
+func (c *Client) SetSearchOpt(ctx context.Context, collectionName string, opt core.QueryOpt) (*core.CollectionStatistics, error) {
+	stat := &core.CollectionStatistics{}
+	if _, err := c.doJSON(ctx, http.MethodPut, collectionPath(collectionName, "search_opt"), opt, stat); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
+
+// TuneCollection stores the nprobe ratio and reorder with the least latency reaching req.TargetRecall.
+func (c *Client) TuneCollection(ctx context.Context, collectionName string, req *core.TuneRequest) (*core.TuneResponse, error) {
+	if req == nil {
+		req = &core.TuneRequest{}
+	}
+	result := &core.TuneResponse{}
+	if _, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "tune"), req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) DumpCollection(ctx context.Context, collectionName string) error {
+	_, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "dump"), nil, nil)
+	return err
+}
+
+func (c *Client) DumpCollectionMetadata(ctx context.Context, collectionName string) error {
+	_, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "dump", "metadata"), nil, nil)
+	return err
+}
+
+func (c *Client) DumpCollectionIndex(ctx context.Context, collectionName string) error {
+	_, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "dump", "index"), nil, nil)
+	return err
+}
+
+func (c *Client) LoadCollection(ctx context.Context, collectionName string) error {
+	_, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "load"), nil, nil)
+	return err
+}
+
+func (c *Client) UnloadCollection(ctx context.Context, collectionName string) error {
+	_, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "unload"), nil, nil)
+	return err
+}
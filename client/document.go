@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"vqlite/core"
+)
+
+func (c *Client) AddDocument(ctx context.Context, collectionName string, doc *core.AddDocumentRequest) error {
+	_, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "document"), doc, nil)
+	return err
+}
+
+func (c *Client) BatchAddDocuments(ctx context.Context, collectionName string, docs []core.AddDocumentRequest) error {
+	req := &core.BatchAddDocumentsRequest{Documents: docs}
+	_, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "document", "batch"), req, nil)
+	return err
+}
+
+// DeleteDocument deletes the documents with vqid and returns the number of deleted documents.
+func (c *Client) DeleteDocument(ctx context.Context, collectionName string, vqid string) (int, error) {
+	req := &core.DeleteDocumentRequest{Vqid: vqid}
+	res, err := c.doJSON(ctx, http.MethodDelete, collectionPath(collectionName, "document"), req, nil)
+	if err != nil {
+		return 0, err
+	}
+	return res.Count, nil
+}
+
+// UpdateDocumentMetadata replaces the metadata of the documents with doc.Vqid and returns the number of updated documents.
+func (c *Client) UpdateDocumentMetadata(ctx context.Context, collectionName string, doc *core.UpdateDocumentMetadataRequest) (int, error) {
+	res, err := c.doJSON(ctx, http.MethodPut, collectionPath(collectionName, "document"), doc, nil)
+	if err != nil {
+		return 0, err
+	}
+	return res.Count, nil
+}
+
+// GetDocumentMetadata returns the metadata of the first document with vqid, or of all of them if all is set.
+func (c *Client) GetDocumentMetadata(ctx context.Context, collectionName string, vqid string, all bool) ([]core.DocumentMetadataResult, error) {
+	query := url.Values{}
+	query.Set("vqid", vqid)
+	if all {
+		query.Set("all", "true")
+	}
+	var documents []core.DocumentMetadataResult
+	path := collectionPath(collectionName, "document") + "?" + query.Encode()
+	if _, err := c.doJSON(ctx, http.MethodGet, path, nil, &documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
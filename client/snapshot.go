@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"vqlite/core"
+)
+
+// SnapshotCollection writes a tar archive of the collection to w and returns its size.
+// An archive cut off or changed on the way fails with an error after being written to w.
+func (c *Client) SnapshotCollection(ctx context.Context, collectionName string, w io.Writer) (int64, error) {
+	resp, err := c.do(ctx, http.MethodPost, collectionPath(collectionName, "snapshot"), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(w, hash), resp.Body)
+	if err != nil {
+		return size, err
+	}
+	if resp.ContentLength >= 0 && size != resp.ContentLength {
+		return size, fmt.Errorf("snapshot archive has %d of %d bytes", size, resp.ContentLength)
+	}
+	if sum := resp.Header.Get(core.SnapshotSha256Header); sum != "" && sum != hex.EncodeToString(hash.Sum(nil)) {
+		return size, errors.New("snapshot archive checksum mismatch")
+	}
+	return size, nil
+}
+
+// RestoreCollection restores the tar archive read from r as the new collection collectionName.
+// The request is not retried, since r can only be read once.
+func (c *Client) RestoreCollection(ctx context.Context, collectionName string, r io.Reader) (*core.SnapshotManifest, error) {
+	resp, err := c.send(ctx, http.MethodPost, collectionPath(collectionName, "restore"), r, false)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, readError(resp)
+	}
+	defer resp.Body.Close()
+	res := &response{}
+	manifest := &core.SnapshotManifest{}
+	if err := decodeResponse(resp.Body, res, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (c *Client) ListSnapshots(ctx context.Context, collectionName string) ([]core.SnapshotManifest, error) {
+	var snapshots []core.SnapshotManifest
+	if _, err := c.doJSON(ctx, http.MethodGet, collectionPath(collectionName, "snapshots"), nil, &snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// CreateSnapshot keeps a named snapshot of the collection on the server, an empty name defaults to the current time.
+func (c *Client) CreateSnapshot(ctx context.Context, collectionName string, snapshotName string) (*core.SnapshotManifest, error) {
+	manifest := &core.SnapshotManifest{}
+	req := &core.CreateSnapshotRequest{Name: snapshotName}
+	if _, err := c.doJSON(ctx, http.MethodPost, collectionPath(collectionName, "snapshots"), req, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (c *Client) RestoreSnapshot(ctx context.Context, collectionName string, snapshotName string) (*core.SnapshotManifest, error) {
+	manifest := &core.SnapshotManifest{}
+	path := collectionPath(collectionName, "snapshots", url.PathEscape(snapshotName), "restore")
+	if _, err := c.doJSON(ctx, http.MethodPost, path, nil, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (c *Client) DeleteSnapshot(ctx context.Context, collectionName string, snapshotName string) error {
+	path := collectionPath(collectionName, "snapshots", url.PathEscape(snapshotName))
+	_, err := c.doJSON(ctx, http.MethodDelete, path, nil, nil)
+	return err
+}
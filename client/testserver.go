@@ -0,0 +1,37 @@
+package client
+
+import (
+	"net/http/httptest"
+	"testing"
+	"vqlite/config"
+	"vqlite/core"
+	routes "vqlite/routers"
+)
+
+// NewTestServer starts VQLite in process with a temp data path and returns a client for it,
+// for unit tests of code using the client. Retries are disabled, so errors show up at once.
+//
+// The server is closed and all its collections and aliases are dropped when the test ends.
+// Collections are kept in process wide state, so tests using it must not run in parallel.
+func NewTestServer(tb testing.TB) *Client {
+	tb.Helper()
+	config.GlobalConfig.ServiceConfig.DataPath = tb.TempDir()
+	srv := httptest.NewServer(routes.InitRouter())
+	tb.Cleanup(func() {
+		srv.Close()
+		for alias := range core.ListAliases(nil) {
+			_ = core.DeleteAlias(alias)
+		}
+		for _, col := range core.VqliteCollectionList.List() {
+			if err := core.DropCollection(col.Name()); err != nil {
+				tb.Logf("drop collection [%s] error: %s", col.Name(), err)
+			}
+		}
+	})
+
+	c, err := New(srv.URL, WithRetry(0, 0, 0))
+	if err != nil {
+		tb.Fatalf("create client error: %s", err)
+	}
+	return c
+}
@@ -0,0 +1,121 @@
+package vqlite
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"vqlite/client"
+	"vqlite/config"
+	"vqlite/core"
+)
+
+const (
+	EvalCmd = "eval"
+)
+
+type eval struct {
+	host       string
+	port       string
+	token      string
+	collection string
+	sampleSize int
+	topk       int
+	nprobes    string
+	reorders   string
+	efSearches string
+}
+
+func (e *eval) execute(args []string, flags *flag.FlagSet) {
+
+	if len(args) < 3 {
+		fmt.Fprintln(os.Stderr, usageLine)
+		return
+	}
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, usageLine)
+	}
+
+	e.formatFlags(args, flags)
+
+	if e.collection == "" {
+		fmt.Fprintln(os.Stderr, "collection is empty")
+		os.Exit(-1)
+	}
+	req := &core.EvalRequest{SampleSize: e.sampleSize, TopK: e.topk}
+	var err error
+	if req.NProbes, err = parseInts(e.nprobes); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid nprobes [%s], err: %s\n", e.nprobes, err.Error())
+		os.Exit(-1)
+	}
+	if req.Reorders, err = parseInts(e.reorders); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid reorders [%s], err: %s\n", e.reorders, err.Error())
+		os.Exit(-1)
+	}
+	if req.EfSearches, err = parseInts(e.efSearches); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid ef_searches [%s], err: %s\n", e.efSearches, err.Error())
+		os.Exit(-1)
+	}
+
+	cli, err := client.New(fmt.Sprintf("http://%s:%s", e.host, e.port), client.WithToken(e.token))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eval collection [%s] failed, err: %s\n", e.collection, err.Error())
+		os.Exit(-1)
+	}
+	result, err := cli.EvalCollection(context.Background(), e.collection, req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eval collection [%s] failed, err: %s\n", e.collection, err.Error())
+		os.Exit(-1)
+	}
+
+	fmt.Printf("collection [%s], vectors: %d, queries: %d, exact search: %.1fms\n",
+		result.CollectionName, result.VectorCount, result.Queries, result.ExactMs)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	// a HNSW collection is searched with ef_search instead of nprobe and reorder
+	if len(result.Results) > 0 && result.Results[0].EfSearch > 0 {
+		fmt.Fprintf(w, "ef_search\trecall@%d\tmean ms\tp99 ms\n", result.TopK)
+		for _, r := range result.Results {
+			fmt.Fprintf(w, "%d\t%.4f\t%.2f\t%.2f\n", r.EfSearch, r.Recall, r.LatencyMeanMs, r.LatencyP99Ms)
+		}
+	} else {
+		fmt.Fprintf(w, "nprobe\treorder\trecall@%d\tmean ms\tp99 ms\n", result.TopK)
+		for _, r := range result.Results {
+			fmt.Fprintf(w, "%d\t%d\t%.4f\t%.2f\t%.2f\n", r.NProbe, r.Reorder, r.Recall, r.LatencyMeanMs, r.LatencyP99Ms)
+		}
+	}
+	w.Flush()
+}
+
+func (e *eval) formatFlags(args []string, flags *flag.FlagSet) {
+	flags.StringVar(&e.host, "host", "127.0.0.1", "VQLite service address")
+	flags.StringVar(&e.port, "port", strconv.Itoa(config.GlobalConfig.ServiceConfig.Port), "VQLite service port")
+	flags.StringVar(&e.token, "token", os.Getenv("VQLITE_TOKEN"), "API key, default $VQLITE_TOKEN")
+	flags.StringVar(&e.collection, "collection", "", "collection name")
+	flags.IntVar(&e.sampleSize, "sample", 0, "number of sampled query vectors, default 100")
+	flags.IntVar(&e.topk, "topk", 0, "recall@topk, default 10")
+	flags.StringVar(&e.nprobes, "nprobes", "", "comma separated nprobe values, default 32,64,128,256")
+	flags.StringVar(&e.reorders, "reorders", "", "comma separated reorder values, default 64,128,256")
+	flags.StringVar(&e.efSearches, "ef_searches", "", "comma separated ef_search values of a HNSW collection, default topk times 1,2,4,8,16")
+	if err := flags.Parse(args[2:]); err != nil {
+		os.Exit(-1)
+	}
+}
+
+// parseInts parses a comma separated list of ints, empty returns nil.
+func parseInts(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	values := make([]int, 0)
+	for _, field := range strings.Split(s, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
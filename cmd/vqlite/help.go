@@ -2,4 +2,7 @@ package vqlite
 
 var usageLine = "Usage:\n" +
 	"Start a VQLite Server: vqlite run \n" +
-	"Train a segment: vqlite train -segmentWorkDir <segmentWorkDir> -numThreads <numThreads>"
+	"Train a segment: vqlite train -segmentWorkDir <segmentWorkDir> -numThreads <numThreads>\n" +
+	"Snapshot a collection: vqlite snapshot -collection <collection> [-output <file>] [-host <host>] [-port <port>] [-token <key>]\n" +
+	"Restore a collection: vqlite restore -collection <collection> -input <file> [-host <host>] [-port <port>] [-token <key>]\n" +
+	"Evaluate the recall of a collection: vqlite eval -collection <collection> [-sample <n>] [-topk <k>] [-nprobes <n,...>] [-reorders <n,...>] [-ef_searches <n,...>] [-host <host>] [-port <port>] [-token <key>]"
@@ -0,0 +1,71 @@
+package vqlite
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"vqlite/client"
+	"vqlite/config"
+)
+
+const (
+	RestoreCmd = "restore"
+)
+
+type restore struct {
+	host       string
+	port       string
+	token      string
+	collection string
+	input      string
+}
+
+func (r *restore) execute(args []string, flags *flag.FlagSet) {
+
+	if len(args) < 3 {
+		fmt.Fprintln(os.Stderr, usageLine)
+		return
+	}
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, usageLine)
+	}
+
+	r.formatFlags(args, flags)
+
+	if r.collection == "" || r.input == "" {
+		fmt.Fprintln(os.Stderr, "collection or input is empty")
+		os.Exit(-1)
+	}
+
+	f, err := os.Open(r.input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open file [%s] failed, err: %s\n", r.input, err.Error())
+		os.Exit(-1)
+	}
+	defer f.Close()
+
+	cli, err := client.New(fmt.Sprintf("http://%s:%s", r.host, r.port), client.WithToken(r.token))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore collection [%s] failed, err: %s\n", r.collection, err.Error())
+		os.Exit(-1)
+	}
+	manifest, err := cli.RestoreCollection(context.Background(), r.collection, f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore collection [%s] failed, err: %s\n", r.collection, err.Error())
+		os.Exit(-1)
+	}
+	fmt.Printf("restore collection [%s] from snapshot of [%s] success, segments: %d\n", r.collection, manifest.Collection, len(manifest.Segments))
+}
+
+func (r *restore) formatFlags(args []string, flags *flag.FlagSet) {
+	flags.StringVar(&r.host, "host", "127.0.0.1", "VQLite service address")
+	flags.StringVar(&r.port, "port", strconv.Itoa(config.GlobalConfig.ServiceConfig.Port), "VQLite service port")
+	flags.StringVar(&r.token, "token", os.Getenv("VQLITE_TOKEN"), "API key, default $VQLITE_TOKEN")
+	flags.StringVar(&r.collection, "collection", "", "name of the restored collection")
+	flags.StringVar(&r.input, "input", "", "snapshot archive file")
+	if err := flags.Parse(args[2:]); err != nil {
+		os.Exit(-1)
+	}
+}
@@ -1,13 +1,23 @@
 package vqlite
 
 import (
+	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 	"vqlite/config"
+	"vqlite/core"
 	routes "vqlite/routers"
+	"vqlite/rpc"
+	"vqlite/utils"
 )
 
 const (
@@ -35,8 +45,106 @@ func (c *run) execute(args []string, flags *flag.FlagSet) {
 	// make go ignore SIGPIPE when all cgo threads set mask of SIGPIPE
 	signal.Ignore(syscall.SIGPIPE)
 
+	var certReloader *utils.CertReloader
+	if serviceConfig := config.GlobalConfig.ServiceConfig; serviceConfig.TLSCertFile != "" {
+		var err error
+		certReloader, err = utils.NewCertReloader(serviceConfig.TLSCertFile, serviceConfig.TLSKeyFile, serviceConfig.TLSClientCAFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("load TLS certificates error")
+		}
+		certReloader.Watch(time.Duration(serviceConfig.TLSReloadInterval) * time.Second)
+	}
+
 	r := routes.InitRouter()
-	r.Run(addr)
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: r,
+	}
+	if certReloader != nil {
+		srv.TLSConfig = certReloader.TLSConfig()
+	}
+	go func() {
+		var err error
+		if certReloader != nil {
+			log.Info().Msgf("VQLite server listening on %s with TLS", addr)
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			log.Info().Msgf("VQLite server listening on %s", addr)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal().Err(err).Msg("VQLite server error")
+		}
+	}()
+
+	var grpcSrv *grpc.Server
+	if grpcPort := config.GlobalConfig.ServiceConfig.GrpcPort; grpcPort > 0 {
+		grpcAddr := fmt.Sprintf("%s:%d", host, grpcPort)
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatal().Err(err).Msg("VQLite gRPC server listen error")
+		}
+		var tlsConfig *tls.Config
+		if certReloader != nil {
+			tlsConfig = certReloader.TLSConfig("h2")
+		}
+		grpcSrv = rpc.NewGRPCServer(tlsConfig)
+		go func() {
+			log.Info().Msgf("VQLite gRPC server listening on %s", grpcAddr)
+			if err := grpcSrv.Serve(lis); err != nil {
+				log.Fatal().Err(err).Msg("VQLite gRPC server error")
+			}
+		}()
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	log.Info().Msgf("received signal %s, shutting down", sig)
+
+	os.Exit(c.shutdown(srv, grpcSrv))
+}
+
+// shutdown stops accepting requests, drains in-flight requests, then flushes and
+// releases all collections. It returns the process exit code.
+func (c *run) shutdown(srv *http.Server, grpcSrv *grpc.Server) int {
+	timeout := time.Duration(config.GlobalConfig.ServiceConfig.ShutdownTimeout) * time.Second
+	exitCode := 0
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), timeout)
+	defer drainCancel()
+	if grpcSrv != nil {
+		go func() {
+			// GracefulStop waits for all RPCs, so cancel the remaining ones after the timeout
+			<-drainCtx.Done()
+			grpcSrv.Stop()
+		}()
+	}
+	if err := srv.Shutdown(drainCtx); err != nil {
+		log.Error().Err(err).Msg("drain requests error")
+		exitCode = 1
+	}
+	if grpcSrv != nil {
+		grpcSrv.GracefulStop()
+		if drainCtx.Err() != nil {
+			log.Error().Err(drainCtx.Err()).Msg("drain gRPC requests error")
+			exitCode = 1
+		}
+	}
+
+	trainCtx, trainCancel := context.WithTimeout(context.Background(), timeout)
+	defer trainCancel()
+	if err := core.Shutdown(trainCtx); err != nil {
+		log.Error().Err(err).Msg("shutdown error")
+		exitCode = 1
+	}
+
+	if exitCode == 0 {
+		log.Info().Msg("VQLite shutdown success")
+	} else {
+		log.Error().Msgf("VQLite shutdown with errors, exit code %d", exitCode)
+	}
+	return exitCode
 }
 
 func (c *run) formatFlags(args []string, flags *flag.FlagSet) {
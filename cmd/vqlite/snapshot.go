@@ -0,0 +1,76 @@
+package vqlite
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"vqlite/client"
+	"vqlite/config"
+)
+
+const (
+	SnapshotCmd = "snapshot"
+)
+
+type snapshot struct {
+	host       string
+	port       string
+	token      string
+	collection string
+	output     string
+}
+
+func (s *snapshot) execute(args []string, flags *flag.FlagSet) {
+
+	if len(args) < 3 {
+		fmt.Fprintln(os.Stderr, usageLine)
+		return
+	}
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, usageLine)
+	}
+
+	s.formatFlags(args, flags)
+
+	if s.collection == "" {
+		fmt.Fprintln(os.Stderr, "collection is empty")
+		os.Exit(-1)
+	}
+	if s.output == "" {
+		s.output = s.collection + ".tar"
+	}
+
+	cli, err := client.New(fmt.Sprintf("http://%s:%s", s.host, s.port), client.WithToken(s.token))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "snapshot collection [%s] failed, err: %s\n", s.collection, err.Error())
+		os.Exit(-1)
+	}
+
+	f, err := os.Create(s.output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "create file [%s] failed, err: %s\n", s.output, err.Error())
+		os.Exit(-1)
+	}
+	defer f.Close()
+	size, err := cli.SnapshotCollection(context.Background(), s.collection, f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "snapshot collection [%s] failed, err: %s\n", s.collection, err.Error())
+		f.Close()
+		_ = os.Remove(s.output)
+		os.Exit(-1)
+	}
+	fmt.Printf("snapshot collection [%s] to %s, %d bytes\n", s.collection, s.output, size)
+}
+
+func (s *snapshot) formatFlags(args []string, flags *flag.FlagSet) {
+	flags.StringVar(&s.host, "host", "127.0.0.1", "VQLite service address")
+	flags.StringVar(&s.port, "port", strconv.Itoa(config.GlobalConfig.ServiceConfig.Port), "VQLite service port")
+	flags.StringVar(&s.token, "token", os.Getenv("VQLITE_TOKEN"), "API key, default $VQLITE_TOKEN")
+	flags.StringVar(&s.collection, "collection", "", "collection name")
+	flags.StringVar(&s.output, "output", "", "output archive file, default <collection>.tar")
+	if err := flags.Parse(args[2:]); err != nil {
+		os.Exit(-1)
+	}
+}
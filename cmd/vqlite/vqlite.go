@@ -36,6 +36,12 @@ func RunVQlite(args []string) {
 		c = &run{}
 	case TrainCmd:
 		c = &train{}
+	case SnapshotCmd:
+		c = &snapshot{}
+	case RestoreCmd:
+		c = &restore{}
+	case EvalCmd:
+		c = &eval{}
 	default:
 		c = &defaultCommand{}
 	}
@@ -16,10 +16,102 @@ type ServiceConfig struct {
 	RunMode              string `mapstructure:"runMode"`
 	DataPath             string `mapstructure:"dataPath"`
 	SegmentVectorMaxSize int64  `mapstructure:"segmentVectorMaxSize"`
+	// GrpcPort serves the gRPC API on this port, 0 disables it
+	GrpcPort int `mapstructure:"grpcPort"`
+	// GrpcMaxMsgSize is the max size in MB of a gRPC request or response
+	GrpcMaxMsgSize int `mapstructure:"grpcMaxMsgSize"`
+	// TLSCertFile and TLSKeyFile serve HTTPS and gRPC over TLS, TLSClientCAFile requires client
+	// certificates signed by its CAs. The files are reloaded every TLSReloadInterval seconds when changed.
+	TLSCertFile       string `mapstructure:"tlsCertFile"`
+	TLSKeyFile        string `mapstructure:"tlsKeyFile"`
+	TLSClientCAFile   string `mapstructure:"tlsClientCAFile"`
+	TLSReloadInterval int    `mapstructure:"tlsReloadInterval"`
+	// LazyLoad only loads collections on first access instead of at startup
+	LazyLoad bool `mapstructure:"lazyLoad"`
+	// CollectionIdleTimeout unloads collections not accessed for this many seconds, 0 disables it
+	CollectionIdleTimeout int `mapstructure:"collectionIdleTimeout"`
+	// CollectionMemoryBudget unloads the least recently used collections when the estimated
+	// memory of loaded collections exceeds this many MB, 0 disables it
+	CollectionMemoryBudget int64 `mapstructure:"collectionMemoryBudget"`
+	// CollectionEvictInterval is the interval in seconds to check idle time, memory budget and ttl of in-memory collections
+	CollectionEvictInterval int `mapstructure:"collectionEvictInterval"`
+	// FlushInterval dumps the metadata and config of changed segments every this many seconds, 0 disables it
+	FlushInterval int `mapstructure:"flushInterval"`
+	// ShutdownTimeout is the seconds to drain requests and to wait for trainings on shutdown
+	ShutdownTimeout int `mapstructure:"shutdownTimeout"`
+}
+
+// APIKeyConfig is an API key, Collections limits it to these collections or aliases, empty allows all.
+type APIKeyConfig struct {
+	Name        string   `mapstructure:"name"`
+	Key         string   `mapstructure:"key"`
+	Role        string   `mapstructure:"role"`
+	Collections []string `mapstructure:"collections"`
+}
+
+type AuthConfig struct {
+	// Enabled requires an API key for every request but /api/ping
+	Enabled bool           `mapstructure:"enabled"`
+	Keys    []APIKeyConfig `mapstructure:"keys"`
+	// DebugKeys may access /metrics and /debug/pprof, as do admin keys not scoped to collections
+	DebugKeys []string `mapstructure:"debugKeys"`
+}
+
+// RateLimit is a token bucket of Rate requests per second holding up to Burst requests, a Rate of 0 disables it.
+type RateLimit struct {
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+}
+
+// RateLimitConfig limits the requests of every API key, or IP without auth, per route class.
+type RateLimitConfig struct {
+	Search RateLimit `mapstructure:"search"`
+	Read   RateLimit `mapstructure:"read"`
+	Write  RateLimit `mapstructure:"write"`
+	Admin  RateLimit `mapstructure:"admin"`
+	// MaxConcurrentSearches is the number of searches run at once, 0 disables it
+	MaxConcurrentSearches int `mapstructure:"maxConcurrentSearches"`
+	// SearchQueueTimeout is the milliseconds a search waits for a slot before it is rejected
+	SearchQueueTimeout int `mapstructure:"searchQueueTimeout"`
+}
+
+// LimitConfig bounds the size of requests, it is checked before the vectors are copied or searched.
+// A value of 0 uses the default.
+type LimitConfig struct {
+	// MaxBodySize is the max size in MB of a JSON request body, snapshot archives are not limited
+	MaxBodySize        int64 `mapstructure:"maxBodySize"`
+	MaxDocumentVectors int   `mapstructure:"maxDocumentVectors"`
+	MaxBatchDocuments  int   `mapstructure:"maxBatchDocuments"`
+	MaxQueryVectors    int   `mapstructure:"maxQueryVectors"`
+	MaxTopK            int   `mapstructure:"maxTopK"`
+	MaxNProbe          int   `mapstructure:"maxNProbe"`
+	MaxReorder         int   `mapstructure:"maxReorder"`
+	MaxEfSearch        int   `mapstructure:"maxEfSearch"`
+	// MaxQueryResults is the max of query vectors times topk of a search
+	MaxQueryResults int `mapstructure:"maxQueryResults"`
+}
+
+// TrainConfig chooses between an incremental and a full training when no train type is asked for.
+// An incremental training assigns the new vectors of a segment to its partitions without training
+// them again, while the vectors added since the last full training are at most IncrementalMaxDelta
+// of the ones it trained on and their mean drifted at most IncrementalMaxDrift from theirs.
+type TrainConfig struct {
+	IncrementalTrain bool `mapstructure:"incrementalTrain"`
+	// IncrementalMaxDelta is the ratio of added to trained vectors, default 0.2
+	IncrementalMaxDelta float64 `mapstructure:"incrementalMaxDelta"`
+	// IncrementalMaxDrift is the cosine distance of the mean of added and trained vectors, default 0.05
+	IncrementalMaxDrift float64 `mapstructure:"incrementalMaxDrift"`
+	// AutoTrainInterval trains segments incrementally every this many seconds when they may be,
+	// full trainings are left to the train API, 0 disables it
+	AutoTrainInterval int `mapstructure:"autoTrainInterval"`
 }
 
 type Config struct {
-	ServiceConfig ServiceConfig `mapstructure:"serviceConfig"`
+	ServiceConfig   ServiceConfig   `mapstructure:"serviceConfig"`
+	AuthConfig      AuthConfig      `mapstructure:"authConfig"`
+	RateLimitConfig RateLimitConfig `mapstructure:"rateLimitConfig"`
+	LimitConfig     LimitConfig     `mapstructure:"limitConfig"`
+	TrainConfig     TrainConfig     `mapstructure:"trainConfig"`
 }
 
 func init() {
@@ -33,6 +125,8 @@ func init() {
 
 	logging.InitLogger()
 
+	viper.SetDefault("trainConfig.incrementalTrain", true)
+
 	if err := viper.ReadInConfig(); err != nil {
 		log.Fatal().Err(err).Msg("read config file error")
 	}
@@ -51,4 +145,72 @@ func init() {
 		log.Info().Msgf("segmentVectorMaxSize is too small, set to default value 10000")
 	}
 
+	if GlobalConfig.ServiceConfig.CollectionEvictInterval <= 0 {
+		GlobalConfig.ServiceConfig.CollectionEvictInterval = 60
+	}
+
+	if GlobalConfig.ServiceConfig.GrpcMaxMsgSize <= 0 {
+		GlobalConfig.ServiceConfig.GrpcMaxMsgSize = 64
+	}
+
+	if (GlobalConfig.ServiceConfig.TLSCertFile == "") != (GlobalConfig.ServiceConfig.TLSKeyFile == "") {
+		log.Fatal().Msg("tlsCertFile and tlsKeyFile must be set together")
+	}
+
+	if GlobalConfig.ServiceConfig.TLSClientCAFile != "" && GlobalConfig.ServiceConfig.TLSCertFile == "" {
+		log.Fatal().Msg("tlsClientCAFile requires tlsCertFile and tlsKeyFile")
+	}
+
+	if GlobalConfig.ServiceConfig.TLSReloadInterval <= 0 {
+		GlobalConfig.ServiceConfig.TLSReloadInterval = 10
+	}
+
+	if GlobalConfig.RateLimitConfig.SearchQueueTimeout < 0 {
+		GlobalConfig.RateLimitConfig.SearchQueueTimeout = 0
+	}
+
+	setDefaultLimits(&GlobalConfig.LimitConfig)
+
+	if GlobalConfig.TrainConfig.IncrementalMaxDelta <= 0 {
+		GlobalConfig.TrainConfig.IncrementalMaxDelta = 0.2
+	}
+
+	if GlobalConfig.TrainConfig.IncrementalMaxDrift <= 0 {
+		GlobalConfig.TrainConfig.IncrementalMaxDrift = 0.05
+	}
+
+	if GlobalConfig.ServiceConfig.ShutdownTimeout <= 0 {
+		GlobalConfig.ServiceConfig.ShutdownTimeout = 30
+	}
+
+}
+
+func setDefaultLimits(limitConfig *LimitConfig) {
+	if limitConfig.MaxBodySize <= 0 {
+		limitConfig.MaxBodySize = 64
+	}
+	if limitConfig.MaxDocumentVectors <= 0 {
+		limitConfig.MaxDocumentVectors = 1024
+	}
+	if limitConfig.MaxBatchDocuments <= 0 {
+		limitConfig.MaxBatchDocuments = 10000
+	}
+	if limitConfig.MaxQueryVectors <= 0 {
+		limitConfig.MaxQueryVectors = 1024
+	}
+	if limitConfig.MaxTopK <= 0 {
+		limitConfig.MaxTopK = 10000
+	}
+	if limitConfig.MaxNProbe <= 0 {
+		limitConfig.MaxNProbe = 100000
+	}
+	if limitConfig.MaxReorder <= 0 {
+		limitConfig.MaxReorder = 100000
+	}
+	if limitConfig.MaxEfSearch <= 0 {
+		limitConfig.MaxEfSearch = 10000
+	}
+	if limitConfig.MaxQueryResults <= 0 {
+		limitConfig.MaxQueryResults = 1000000
+	}
 }
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"github.com/rs/zerolog/log"
+	"sync"
+	"vqlite/config"
+	vqerrors "vqlite/errors"
+	"vqlite/utils"
+)
+
+// AliasesFileName is the file under the data path that keeps all aliases.
+const AliasesFileName = ".aliases.gob"
+
+// aliasLock serializes alias changes, so the aliases file always matches VqliteCollectionList.
+var aliasLock sync.Mutex
+
+func aliasesFilePath() string {
+	return utils.Join(config.GlobalConfig.ServiceConfig.DataPath, AliasesFileName)
+}
+
+// SetAlias points alias to collectionName, an existing alias is switched atomically.
+func SetAlias(alias string, collectionName string) error {
+	if alias == "" {
+		return vqerrors.InvalidArgument("alias is empty")
+	}
+	if collectionName == "" {
+		return vqerrors.InvalidArgument("collection name is empty")
+	}
+	aliasLock.Lock()
+	defer aliasLock.Unlock()
+
+	if _, ok := VqliteCollectionList.Get(alias); ok || CheckCollection(alias) == nil {
+		return vqerrors.AlreadyExists("alias [%s] conflicts with collection [%s]", alias, alias)
+	}
+	if _, ok := VqliteCollectionList.ListAliases()[collectionName]; ok {
+		return vqerrors.InvalidArgument("collection [%s] is an alias", collectionName)
+	}
+	if err := CheckCollection(collectionName); err != nil {
+		return err
+	}
+
+	VqliteCollectionList.SetAlias(alias, collectionName)
+	if err := dumpAliases(); err != nil {
+		return err
+	}
+	log.Info().Msgf("set alias [%s] to collection [%s]", alias, collectionName)
+	return nil
+}
+
+func DeleteAlias(alias string) error {
+	aliasLock.Lock()
+	defer aliasLock.Unlock()
+
+	if _, ok := VqliteCollectionList.ListAliases()[alias]; !ok {
+		return vqerrors.NotFound("alias [%s] not exists", alias)
+	}
+	VqliteCollectionList.DeleteAlias(alias)
+	if err := dumpAliases(); err != nil {
+		return err
+	}
+	log.Info().Msgf("delete alias [%s]", alias)
+	return nil
+}
+
+func GetAlias(alias string) (string, error) {
+	collectionName, ok := VqliteCollectionList.ListAliases()[alias]
+	if !ok {
+		return "", vqerrors.NotFound("alias [%s] not exists", alias)
+	}
+	return collectionName, nil
+}
+
+// ListAliases returns the aliases of the collections allow returns true for, all of them for a nil allow.
+func ListAliases(allow func(collectionName string) bool) map[string]string {
+	aliases := VqliteCollectionList.ListAliases()
+	if allow == nil {
+		return aliases
+	}
+	allowed := make(map[string]string, len(aliases))
+	for alias, collectionName := range aliases {
+		if allow(collectionName) {
+			allowed[alias] = collectionName
+		}
+	}
+	return allowed
+}
+
+// GetCollectionAliases returns all aliases pointing to collectionName.
+func GetCollectionAliases(collectionName string) []string {
+	aliases := make([]string, 0)
+	for alias, name := range VqliteCollectionList.ListAliases() {
+		if name == collectionName {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+func dumpAliases() error {
+	err := utils.Dump(VqliteCollectionList.ListAliases(), aliasesFilePath())
+	if err != nil {
+		log.Error().Err(err).Msg("dump aliases error")
+	}
+	return err
+}
+
+func LoadAliases() {
+	filename := aliasesFilePath()
+	if !utils.Exists(filename) {
+		return
+	}
+	aliases := make(map[string]string)
+	if err := utils.Load(&aliases, filename); err != nil {
+		log.Error().Err(err).Msg("load aliases error")
+		return
+	}
+	for alias, collectionName := range aliases {
+		VqliteCollectionList.SetAlias(alias, collectionName)
+	}
+	log.Info().Msgf("load %d aliases", len(aliases))
+}
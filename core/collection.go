@@ -4,6 +4,7 @@ import "C"
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/sync/errgroup"
@@ -16,17 +17,28 @@ import (
 	"time"
 	"vqlite/config"
 	scann "vqlite/engine/go-scann"
+	vqerrors "vqlite/errors"
 	"vqlite/utils"
 )
 
 type Collection struct {
-	Name              string
+	// name is changed by renames while searches, the evictor and the flusher read it, so it is only read with Name
+	name              atomic.Value
 	Segments          []*Segment
 	IndexType         string
 	MaxSegmentId      uint64
 	CollectionWorkDir string
 	Dim               int
 	lock              sync.RWMutex
+	lifecycle         collectionLifecycle
+	// config is kept in the collection dir, configLock guards it since searches read it without lock
+	config     CollectionConfig
+	configLock sync.RWMutex
+
+	// Storage is scann.StorageMemory for a collection which never touches disk, it is dropped
+	// once not accessed for TTL, 0 keeps it until dropped
+	Storage string
+	TTL     time.Duration
 }
 
 // NewCollection creates a new Collection with the specified name and dimension.
@@ -39,35 +51,52 @@ type Collection struct {
 // - *Collection: The newly created Collection object.
 // - error: An error if the collection already exists or if the dimension is less than 0.
 func NewCollection(name string, dim int) (*Collection, error) {
+	return newCollection(name, dim, scann.StorageFile, 0)
+}
+
+// newCollection creates a new Collection kept in storage, the dir of an in-memory collection is never created.
+func newCollection(name string, dim int, storage string, ttl time.Duration) (*Collection, error) {
+	if err := checkCollectionName(name); err != nil {
+		return nil, err
+	}
 	dataPath := config.GlobalConfig.ServiceConfig.DataPath
 	collectionPath := utils.Join(dataPath, name)
 	// check if collection exists
 	if _, ok := VqliteCollectionList.Get(name); ok {
-		return nil, fmt.Errorf("collection is exist")
+		return nil, vqerrors.AlreadyExists("collection [%s] already exists", name)
 	}
 
 	if dim < 0 {
-		return nil, fmt.Errorf("NewCollection dim can not smaller than 0")
+		return nil, vqerrors.InvalidArgument("NewCollection dim can not smaller than 0")
 	}
 
 	// create collection dir
-	if !utils.IsDir(collectionPath) {
+	if storage != scann.StorageMemory && !utils.IsDir(collectionPath) {
 		utils.CreateDirPath(collectionPath)
 	}
 
 	col := &Collection{
-		Name:              name,
-		IndexType:         "ScaNN",
+		IndexType:         scann.IndexTypeScaNN,
 		MaxSegmentId:      0,
 		Segments:          make([]*Segment, 0),
 		CollectionWorkDir: collectionPath,
 		Dim:               dim,
+		Storage:           storage,
+		TTL:               ttl,
 	}
+	col.name.Store(name)
+	_ = col.setState(CollectionStateUnloaded, nil)
+	col.Touch()
 
 	VqliteCollectionList.Add(col) // add to global collection map
 	return col, nil
 }
 
+// Name returns the current name of the collection.
+func (c *Collection) Name() string {
+	return c.name.Load().(string)
+}
+
 // AddNewSegment adds a new segment to the collection.
 //
 // This function does the following:
@@ -79,7 +108,10 @@ func NewCollection(name string, dim int) (*Collection, error) {
 // Returns the newly created segment or nil if there was an error.
 func (c *Collection) AddNewSegment() *Segment {
 	segmentWorkDir := utils.Join(c.CollectionWorkDir, fmt.Sprintf("segment_%d", c.MaxSegmentId))
-	newSegment, err := NewSegment(c.MaxSegmentId, segmentWorkDir, c.Dim)
+	indexParams := c.Config().TrainOpt.indexParams()
+	indexParams.Storage = c.Storage
+	indexParams.IndexType = c.IndexType
+	newSegment, err := NewSegment(c.MaxSegmentId, segmentWorkDir, c.Dim, indexParams)
 	if err != nil {
 		log.Error().Err(err).Msg("create new segment error")
 
@@ -140,6 +172,8 @@ func (c *Collection) GetSealedSegments() []*Segment {
 // Returns:
 // - A slice of *Segment containing the searchable segments.
 func (c *Collection) GetSearchableSegments() []*Segment {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 	searchableSegments := make([]*Segment, 0)
 	for _, seg := range c.Segments {
 		if seg.IsSearchable() {
@@ -149,59 +183,123 @@ func (c *Collection) GetSearchableSegments() []*Segment {
 	return searchableSegments
 }
 
+// segmentSearchResult is the result of the search of one segment.
+type segmentSearchResult struct {
+	segmentId uint64
+	results   [][]scann.VidScore
+	nprobe    int
+	took      time.Duration
+	err       error
+}
+
 // Search searches for query vectors in the collection.
 //
+// Every segment is searched concurrently until ctx is done or opt.Timeout seconds passed, segments
+// which did not answer by then are abandoned. Without opt.AllowPartial the search fails with the error
+// of the first failed segment, or a deadline exceeded error. With opt.AllowPartial the results of the
+// healthy segments are returned flagged as partial, with the failed segments and their errors, unless
+// all segments failed. A canceled ctx, e.g. a closed client connection, always fails the search.
+// With opt.Debug the response explains how every segment answered.
+//
 // Parameters:
+// - ctx: The context of the request.
 // - queryVecs: An array of query vectors to search for.
 // - opt: The query options.
 //
 // Returns:
-// - [][]SearchResult: A 2D array of search results, where each inner array represents the search results for a query vector.
+// - *SearchResponse: The search results, where each inner array represents the search results for a query vector.
 // - error: An error if any occurred during the search.
-func (c *Collection) Search(queryVecs []float32, opt QueryOpt) ([][]SearchResult, error) {
+func (c *Collection) Search(ctx context.Context, queryVecs []float32, opt QueryOpt) (*SearchResponse, error) {
 	searchableSegments := c.GetSearchableSegments()
+	// segments may be swapped by a restore while searching, so only use the segments searched
+	searchedSegments := make(map[uint64]*Segment, len(searchableSegments))
+	for _, seg := range searchableSegments {
+		searchedSegments[seg.SegmentConfig.SegmentId] = seg
+	}
 
-	resultsCh := make(chan [][]scann.VidScore, len(searchableSegments))
-
-	tempResults := make([][][]scann.VidScore, 0, len(searchableSegments))
+	searchStart := time.Now()
+	searchCtx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(opt.Timeout))
+	defer cancel()
 
-	eg, ctx := errgroup.WithContext(context.Background())
 	// get topK vectors from each segment
 	// and merge them to one result
-
-	for i, seg := range searchableSegments {
-		_, seg := i, seg // https://golang.org/doc/faq#closures_and_goroutines
-		eg.Go(func() error {
-			timeoutCtx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(opt.Timeout))
-			defer cancel()
-			searchResults, err := seg.Search(queryVecs, opt)
-			if err != nil {
-				return err
-			}
-			select {
-			case <-timeoutCtx.Done():
-				return fmt.Errorf("search index timeout")
-			//case resultsCh <- searchResults.([][]scann.VidScore):
-			case resultsCh <- searchResults:
-				return nil
+	resultsCh := make(chan segmentSearchResult, len(searchableSegments))
+	for _, seg := range searchableSegments {
+		go func(seg *Segment) {
+			start := time.Now()
+			segmentOpt := opt
+			segmentOpt.NProbe = seg.NProbe(opt)
+			searchResults, err := seg.Search(searchCtx, queryVecs, segmentOpt)
+			resultsCh <- segmentSearchResult{
+				segmentId: seg.SegmentConfig.SegmentId,
+				results:   searchResults,
+				nprobe:    segmentOpt.NProbe,
+				took:      time.Since(start),
+				err:       err,
 			}
-		})
+		}(seg)
 	}
 
-	// wait for all search tasks done
-	if err := eg.Wait(); err != nil {
-		log.Warn().Err(err).Msg("search error")
-		return nil, err
+	// every segment search returns once searchCtx is done
+	tempResults := make([][][]scann.VidScore, 0, len(searchableSegments))
+	failedSegments := make([]FailedSegment, 0)
+	var searchErr error
+	timedOut := 0
+	// debug of every segment by segment id, nil without opt.Debug
+	var segmentsDebug map[uint64]*SegmentSearchDebug
+	if opt.Debug {
+		segmentsDebug = make(map[uint64]*SegmentSearchDebug, len(searchableSegments))
+	}
+	for range searchableSegments {
+		result := <-resultsCh
+		if segmentsDebug != nil {
+			segmentsDebug[result.segmentId] = newSegmentSearchDebug(searchedSegments[result.segmentId], &result)
+		}
+		if result.err == nil {
+			tempResults = append(tempResults, result.results)
+			continue
+		}
+		failedSegments = append(failedSegments, FailedSegment{
+			SegmentId: result.segmentId,
+			Code:      vqerrors.CodeOf(result.err),
+			Error:     result.err.Error(),
+		})
+		if errors.Is(result.err, context.DeadlineExceeded) || errors.Is(result.err, context.Canceled) {
+			timedOut++
+			continue
+		}
+		log.Warn().Err(result.err).Msgf("search collection [%s] segment [%d] error", c.Name(), result.segmentId)
+		if searchErr == nil {
+			searchErr = result.err
+			if !opt.AllowPartial {
+				// stop the other segments, the search fails anyway
+				cancel()
+			}
+		}
 	}
 
-	close(resultsCh)
-
-	for result := range resultsCh {
-		tempResults = append(tempResults, result)
+	if ctx.Err() == context.Canceled {
+		searchCanceledTotal.WithLabelValues(c.Name()).Inc()
+		return nil, vqerrors.Wrap(vqerrors.CodeCanceled, ctx.Err(), "search collection [%s] canceled", c.Name())
+	}
+	if searchErr != nil && (!opt.AllowPartial || len(tempResults) == 0) {
+		return nil, searchErr
+	}
+	if timedOut > 0 {
+		searchTimeoutsTotal.WithLabelValues(c.Name()).Inc()
+		if !opt.AllowPartial || len(tempResults) == 0 {
+			return nil, vqerrors.Wrap(vqerrors.CodeDeadlineExceeded, context.DeadlineExceeded,
+				"search collection [%s] timeout, %d of %d segments did not answer", c.Name(), timedOut, len(searchableSegments))
+		}
+	}
+	partial := len(failedSegments) > 0
+	if partial {
+		searchPartialTotal.WithLabelValues(c.Name()).Inc()
+		log.Warn().Msgf("search collection [%s] returns partial results without %d of %d segments", c.Name(), len(failedSegments), len(searchableSegments))
 	}
 
 	if len(searchableSegments) < 1 || len(tempResults) == 0 || len(tempResults[0]) == 0 {
-		return nil, fmt.Errorf("index current unavailable")
+		return nil, vqerrors.NotReady("index current unavailable")
 	}
 	// merge search results from all segments
 	vecScoreResults := make([][]scann.VidScore, len(queryVecs)/c.Dim)
@@ -226,12 +324,18 @@ func (c *Collection) Search(queryVecs []float32, opt QueryOpt) ([][]SearchResult
 		// get vqid from db
 		for _, vecScore := range vecScoreResult {
 			segmentId := vecScore.From
-			seg := c.GetSegmentBySegmentId(segmentId)
+			seg, ok := searchedSegments[segmentId]
+			if !ok {
+				continue
+			}
 			vectorId := vecScore.Vid
 			docId, extra := utils.DecodeVectorId(vectorId)
 			document := seg.SegmentMetadata.GetByid(int(docId))
 			// document == nil means this doc is deleted
 			if document == nil {
+				if segmentsDebug != nil {
+					segmentsDebug[segmentId].DroppedDeleted++
+				}
 				continue
 			}
 			var resultMetadata map[string]interface{}
@@ -247,9 +351,49 @@ func (c *Collection) Search(queryVecs []float32, opt QueryOpt) ([][]SearchResult
 				Tag:      extra,
 			}
 			results[i] = append(results[i], *searchResultItem)
+			if segmentsDebug != nil {
+				segmentsDebug[segmentId].Merged++
+			}
+		}
+	}
+	response := &SearchResponse{Results: results, Partial: partial}
+	if partial {
+		response.FailedSegments = failedSegments
+	}
+	if segmentsDebug != nil {
+		response.Debug = &SearchDebug{
+			Opt:      opt,
+			TookMs:   durationMs(time.Since(searchStart)),
+			Segments: make([]SegmentSearchDebug, 0, len(segmentsDebug)),
 		}
+		for _, segmentDebug := range segmentsDebug {
+			response.Debug.Segments = append(response.Debug.Segments, *segmentDebug)
+		}
+		sort.Slice(response.Debug.Segments, func(i, j int) bool {
+			return response.Debug.Segments[i].SegmentId < response.Debug.Segments[j].SegmentId
+		})
+	}
+	return response, nil
+}
+
+// newSegmentSearchDebug returns the debug of the search of seg, with the search mode of its index.
+func newSegmentSearchDebug(seg *Segment, result *segmentSearchResult) *SegmentSearchDebug {
+	segmentDebug := &SegmentSearchDebug{SegmentId: result.segmentId, TookMs: durationMs(result.took), NProbe: result.nprobe}
+	if result.err != nil {
+		segmentDebug.Error = result.err.Error()
+		return segmentDebug
 	}
-	return results, nil
+	for _, queryResults := range result.results {
+		segmentDebug.Candidates += len(queryResults)
+	}
+	if stat, err := seg.Statistics(); err == nil {
+		segmentDebug.IsBrute = stat.IndexStatistics.IsBrute
+	}
+	return segmentDebug
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
 }
 
 func (c *Collection) GetSegmentBySegmentId(segmentId uint64) *Segment {
@@ -274,8 +418,14 @@ func (c *Collection) GetSegmentBySegmentId(segmentId uint64) *Segment {
 // DeleteDocument deletes a document from the collection.
 //
 // It takes a string parameter vqid, which represents the unique identifier of the document to be deleted.
-// It returns an integer representing the number of documents deleted.
-func (c *Collection) DeleteDocument(vqid string) int {
+// It returns an integer representing the number of documents deleted,
+// or an error if the collection was unloaded after it was looked up.
+func (c *Collection) DeleteDocument(vqid string) (int, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if !c.IsLoaded() {
+		return 0, vqerrors.NotReady("collection [%s] is not loaded", c.Name())
+	}
 	deletedCount := 0
 	for _, seg := range c.Segments {
 		deleted := seg.DeleteDocument(vqid)
@@ -283,20 +433,26 @@ func (c *Collection) DeleteDocument(vqid string) int {
 			deletedCount += 1
 		}
 	}
-	return deletedCount
+	return deletedCount, nil
 }
 
 // UpdateDocumentMetadata updates the metadata of a document in the collection.
 //
 // It takes a pointer to an UpdateDocumentMetadataRequest struct as a parameter.
-// The function returns an integer representing the number of documents whose metadata was updated.
-func (c *Collection) UpdateDocumentMetadata(document *UpdateDocumentMetadataRequest) int {
+// The function returns an integer representing the number of documents whose metadata was updated,
+// or an error if the collection was unloaded after it was looked up.
+func (c *Collection) UpdateDocumentMetadata(document *UpdateDocumentMetadataRequest) (int, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if !c.IsLoaded() {
+		return 0, vqerrors.NotReady("collection [%s] is not loaded", c.Name())
+	}
 	updatedCount := 0
 	for _, seg := range c.Segments {
 		segmentUpdatedCount := seg.UpdateDocumentMetadata(document)
 		updatedCount += segmentUpdatedCount
 	}
-	return updatedCount
+	return updatedCount, nil
 }
 
 // GetDocumentMetadata retrieves the metadata of a document from the collection.
@@ -335,22 +491,36 @@ func (c *Collection) GetDocumentMetadata(vqid string, checkDuplicate bool) []Doc
 //
 // It checks if there is an insertable segment available. If not, it creates a new segment.
 // Then, it adds the document to the segment.
-func (c *Collection) AddDocument(document *AddDocumentRequest) {
+// It returns an error if the collection is not loaded or the index rejects the vectors.
+func (c *Collection) AddDocument(document *AddDocumentRequest) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	// an unloaded collection has no segments, adding would overwrite segment_0 on disk
+	if !c.IsLoaded() {
+		return vqerrors.NotReady("collection [%s] is not loaded", c.Name())
+	}
 	seg := c.GetInsertableSegment()
-	seg.AddDocument(document)
+	if seg == nil {
+		return vqerrors.Internal("collection [%s] create new segment failed", c.Name())
+	}
+	return seg.AddDocument(document)
 }
 
 // BatchAddDocuments adds a batch of documents to the collection.
 //
 // It takes a pointer to a BatchAddDocumentsRequest struct as a parameter.
-// There is no return value.
-func (c *Collection) BatchAddDocuments(documents *BatchAddDocumentsRequest) {
+// It returns an error if the collection is not loaded or the index rejects the vectors.
+func (c *Collection) BatchAddDocuments(documents *BatchAddDocumentsRequest) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	if !c.IsLoaded() {
+		return vqerrors.NotReady("collection [%s] is not loaded", c.Name())
+	}
 	seg := c.GetInsertableSegment()
-	seg.BatchAddDocuments(documents)
+	if seg == nil {
+		return vqerrors.Internal("collection [%s] create new segment failed", c.Name())
+	}
+	return seg.BatchAddDocuments(documents)
 }
 
 // Statistics calculates and returns the statistics of the collection.
@@ -363,9 +533,21 @@ func (c *Collection) BatchAddDocuments(documents *BatchAddDocumentsRequest) {
 // the collection, the list of segment statistics, the total number of segments,
 // the total index size, and the total number of documents in the collection.
 func (c *Collection) Statistics() *CollectionStatistics {
-
+	lastFlushAt, lastFlushError := c.LastFlush()
+	collectionConfig := c.Config()
 	collectionStatistics := &CollectionStatistics{
-		CollectionName: c.Name,
+		CollectionName: c.Name(),
+		IndexType:      c.IndexType,
+		Aliases:        GetCollectionAliases(c.Name()),
+		LoadStatus:     c.LoadStatus(),
+		LastAccess:     c.LastAccess().Unix(),
+		LastFlushAt:    unixOrZero(lastFlushAt),
+		LastFlushError: lastFlushError,
+		SearchOpt:      collectionConfig.SearchOpt,
+		Tuning:         collectionConfig.Tuning,
+		TrainOpt:       collectionConfig.TrainOpt,
+		Storage:        c.Storage,
+		TTL:            int64(c.TTL / time.Second),
 		Segments:       make([]SegmentStatistics, 0),
 		SegmentCount:   0,
 		TotalIndexSize: 0,
@@ -375,12 +557,15 @@ func (c *Collection) Statistics() *CollectionStatistics {
 		segmentStatistics, err := seg.Statistics()
 		if err != nil {
 			log.Error().Err(err).Msg("get segment statistics error")
+			continue
 		}
 		collectionStatistics.Segments = append(collectionStatistics.Segments, *segmentStatistics)
+		collectionStatistics.Dirty = collectionStatistics.Dirty || segmentStatistics.Dirty
 		collectionStatistics.SegmentCount += 1
 		collectionStatistics.TotalIndexSize += segmentStatistics.IndexStatistics.IndexSize
 		collectionStatistics.VectorCount += uint64(segmentStatistics.VectorCount)
 		collectionStatistics.DocCount += uint64(segmentStatistics.DocCount)
+		collectionStatistics.MemorySize += segmentStatistics.VectorCount * int64(segmentStatistics.IndexStatistics.VecDim) * 4
 	}
 	return collectionStatistics
 }
@@ -391,10 +576,19 @@ func (c *Collection) Statistics() *CollectionStatistics {
 // Parameters:
 // - numThreads: The number of threads to use for training.
 // - ignoreCheck: Whether to ignore the memory check and train the index regardless.
+// - opt: The training parameters, unset ones are taken from the collection config.
 //
 // Return:
 // - An error if there was an issue during training, otherwise nil.
-func (c *Collection) Train(numThreads int, ignoreCheck bool) error {
+func (c *Collection) Train(numThreads int, ignoreCheck bool, opt TrainOpt) error {
+	collectionConfig := c.Config()
+	opt = opt.withDefaults(collectionConfig.TrainOpt)
+	if err := opt.check(); err != nil {
+		return err
+	}
+	if err := opt.checkIndexType(collectionConfig.IndexType); err != nil {
+		return err
+	}
 
 	if utils.GetCpuCount() < numThreads {
 		numThreads = 0
@@ -413,15 +607,27 @@ func (c *Collection) Train(numThreads int, ignoreCheck bool) error {
 				requiredMemorySize := uint64(float64(indexStatistics.VidSize*int64(indexStatistics.VecDim)*4) * 1.5) // estimated memory size
 				availableMemory := utils.GetAvailableMemory()                                                        // available memory size
 				if availableMemory < requiredMemorySize {                                                            // if available memory is not enough, skip training
-					err := fmt.Errorf("no enough memory to train, require %d, availableMemory %d, segmentId %d", requiredMemorySize, availableMemory, seg.SegmentConfig.SegmentId)
+					err := vqerrors.ResourceExhausted("no enough memory to train, require %d, availableMemory %d, segmentId %d", requiredMemorySize, availableMemory, seg.SegmentConfig.SegmentId)
 					log.Error().Err(err).Msg("train error")
 					return err
 				}
 			}
-			err := seg.Train(numThreads) // train index
+			if opt.Nlist > 0 && int64(opt.Nlist) > indexStatistics.VidSize {
+				return vqerrors.InvalidArgument("nlist %d is larger than the %d vectors of segment %d", opt.Nlist, indexStatistics.VidSize, seg.SegmentConfig.SegmentId)
+			}
+			trainParams := opt.trainParams()
+			// add trainings keep the partitions, so nlist only applies to new ones
+			if trainParams.Type, err = seg.chooseTrainType(opt.TrainType, *indexStatistics); err != nil {
+				return err
+			}
+			if trainParams.Type == scann.TrainTypeAdd {
+				trainParams.Nlist = 0
+			}
+			err = seg.Train(numThreads, opt.indexParams(), trainParams) // train index
 			if err != nil {
 				return err
 			}
+			trainTotal.WithLabelValues(c.Name(), trainParams.Type).Inc()
 			seg.SetHasNewIndex() // mark segment has new index
 		}
 	}
@@ -445,18 +651,154 @@ func (c *Collection) Drop() error {
 			return err
 		}
 	}
-	err := utils.DeleteDir(c.CollectionWorkDir)
+	if !c.InMemory() {
+		if err := utils.DeleteDir(c.CollectionWorkDir); err != nil {
+			return err
+		}
+	}
+	// the snapshots go with the collection, a new one of the same name must not list or restore them
+	if utils.IsDir(collectionSnapshotsPath(c.Name())) {
+		if err := utils.DeleteDir(collectionSnapshotsPath(c.Name())); err != nil {
+			return err
+		}
+	}
+	VqliteCollectionList.Delete(c.Name())
+	return nil
+}
+
+// Rename renames the collection and its dir while the collection stays loaded.
+//
+// Writes are blocked while the segments are dumped, the dir is renamed and the segments are
+// reloaded from the new dir. VqliteCollectionList and the aliases of the collection are
+// switched to the new name in one step, snapshots of the collection are moved along.
+//
+// Parameters:
+// - newName: The new name of the collection, it must not be used by a collection or alias.
+//
+// Returns:
+// - error: An error if the dir can not be renamed or the segments can not be reloaded.
+func (c *Collection) Rename(newName string) error {
+	aliasLock.Lock()
+	defer aliasLock.Unlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.IsLoaded() {
+		return vqerrors.NotReady("collection [%s] is not loaded", c.Name())
+	}
+	if err := c.dumpAll(); err != nil {
+		return err
+	}
+
+	oldName := c.Name()
+	oldWorkDir := c.CollectionWorkDir
+	newWorkDir := utils.Join(config.GlobalConfig.ServiceConfig.DataPath, newName)
+	if err := os.Rename(oldWorkDir, newWorkDir); err != nil {
+		log.Error().Err(err).Msgf("rename collection [%s] to [%s] error", oldName, newName)
+		return err
+	}
+
+	// the indexes keep their work dir, so the segments have to be loaded again from the new dir
+	c.CollectionWorkDir = newWorkDir
+	newSegments, err := c.loadSegmentsFromWorkDir()
 	if err != nil {
+		c.CollectionWorkDir = oldWorkDir
+		_ = os.Rename(newWorkDir, oldWorkDir)
+		log.Error().Err(err).Msgf("rename collection [%s] to [%s] error", oldName, newName)
 		return err
 	}
-	VqliteCollectionList.Delete(c.Name)
+	oldSegments := c.swapSegments(newSegments)
+	c.name.Store(newName)
+	VqliteCollectionList.Rename(oldName, newName)
+	_ = dumpAliases()
+
+	if utils.IsDir(collectionSnapshotsPath(oldName)) {
+		if err := os.Rename(collectionSnapshotsPath(oldName), collectionSnapshotsPath(newName)); err != nil {
+			log.Warn().Err(err).Msgf("move snapshots of collection [%s] error", oldName)
+		}
+	}
+
+	releaseSegments(oldSegments)
+	log.Info().Msgf("rename collection [%s] to [%s] success", oldName, newName)
+	return nil
+}
+
+// Clone copies the collection to a new collection named newName and loads it.
+//
+// Segment files are hard linked when the file system supports it, both collections
+// unshare linked files before they are written again.
+//
+// Parameters:
+// - newName: The name of the new collection, it must not be used by a collection or alias.
+//
+// Returns:
+// - *Collection: The new collection.
+// - error: An error if the segment files can not be copied or the new collection can not be created.
+func (c *Collection) Clone(newName string) (*Collection, error) {
+	dataPath := config.GlobalConfig.ServiceConfig.DataPath
+	newWorkDir := utils.Join(dataPath, newName)
+	// copy into a hidden temp dir first, so a partial clone is never loaded as a collection
+	tempPath := utils.Join(dataPath, fmt.Sprintf(".clone_%s_%d", newName, time.Now().UnixNano()))
+
+	c.lock.Lock()
+	if err := c.dumpAll(); err != nil {
+		c.lock.Unlock()
+		return nil, err
+	}
+	err := utils.LinkOrCopyDir(c.CollectionWorkDir, tempPath)
+	for _, seg := range c.Segments {
+		seg.SetFilesShared()
+	}
+	dim := c.Dim
+	c.lock.Unlock()
+
+	if err != nil {
+		_ = utils.DeleteDir(tempPath)
+		log.Error().Err(err).Msgf("clone collection [%s] to [%s] error", c.Name(), newName)
+		return nil, err
+	}
+	if err := os.Rename(tempPath, newWorkDir); err != nil {
+		_ = utils.DeleteDir(tempPath)
+		return nil, err
+	}
+
+	col, err := NewCollection(newName, dim)
+	if err != nil {
+		_ = utils.DeleteDir(newWorkDir)
+		return nil, err
+	}
+	if err := col.Load(); err != nil {
+		discardRestored(col)
+		return nil, err
+	}
+	log.Info().Msgf("clone collection [%s] to [%s] success", c.Name(), newName)
+	return col, nil
+}
+
+// InMemory reports whether the collection is kept in memory only.
+func (c *Collection) InMemory() bool {
+	return c.Storage == scann.StorageMemory
+}
+
+// checkOnDisk returns an error for op on an in-memory collection, which has no files to work on.
+func (c *Collection) checkOnDisk(op string) error {
+	if c.InMemory() {
+		return vqerrors.InvalidArgument("collection [%s] is kept in memory, %s is not supported", c.Name(), op)
+	}
 	return nil
 }
 
-func (c *Collection) Dump() {
+// Dump dumps the metadata and config of all segments, a failed segment does not stop
+// the others from being dumped, the first error is returned.
+func (c *Collection) Dump() error {
+	var firstErr error
 	for _, col := range c.Segments {
-		col.Dump()
+		if err := col.Dump(); err != nil && firstErr == nil {
+			firstErr = err
+		}
 	}
+	c.recordFlush(firstErr)
+	return firstErr
 }
 
 func (c *Collection) DumpMetadata() {
@@ -465,9 +807,36 @@ func (c *Collection) DumpMetadata() {
 	}
 }
 
-func (c *Collection) DumpIndex() {
+func (c *Collection) DumpIndex() error {
+	var firstErr error
 	for _, col := range c.Segments {
-		col.DumpIndex()
+		if err := col.DumpIndex(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// dumpAll dumps the metadata, config and index of every segment, for copies taken from the files
+// which must not see a half written dump. It returns the first error.
+func (c *Collection) dumpAll() error {
+	if err := c.Dump(); err != nil {
+		log.Error().Err(err).Msgf("dump collection [%s] error", c.Name())
+		return err
+	}
+	if err := c.DumpIndex(); err != nil {
+		log.Error().Err(err).Msgf("dump index of collection [%s] error", c.Name())
+		return err
+	}
+	return nil
+}
+
+// discardRestored removes a collection which failed to load after its dir was moved into place by
+// a restore or clone, so the name can be used again.
+func discardRestored(col *Collection) {
+	VqliteCollectionList.Delete(col.Name())
+	if err := utils.DeleteDir(col.CollectionWorkDir); err != nil {
+		log.Warn().Err(err).Msgf("remove dir of collection [%s] error", col.Name())
 	}
 }
 
@@ -481,36 +850,121 @@ func (c *Collection) LoadIndex() {
 	}
 }
 
-func (c *Collection) Load() {
+func (c *Collection) Load() error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	if c.Segments != nil && len(c.Segments) > 0 {
+	if c.IsLoaded() || (c.Segments != nil && len(c.Segments) > 0) {
 		fmt.Println("collection segments is not nil", c.Segments)
-		return
+		return c.setState(CollectionStateLoaded, nil)
+	}
+	if err := c.setState(CollectionStateLoading, nil); err != nil {
+		return err
 	}
 	// load segments
 	segmentsDirs, err := os.ReadDir(c.CollectionWorkDir)
 	//remove useless files or dir
-	utils.FilterValidSegmentDirs(segmentsDirs)
+	segmentsDirs = utils.FilterValidSegmentDirs(segmentsDirs)
 	// sort with numbers
 	utils.SortFileNameAscend(segmentsDirs)
 
 	if err != nil {
 		log.Error().Err(err).Msg("load collection error")
-		return
+		_ = c.setState(CollectionStateFailed, err)
+		return err
 	}
 
-	c.LoadSegments(segmentsDirs)
+	segments, err := c.loadSegments(segmentsDirs)
+	if err != nil {
+		log.Error().Err(err).Msg("load collection error")
+		_ = c.setState(CollectionStateFailed, err)
+		return err
+	}
+	c.Segments = segments
+	c.loadConfig()
 
 	if len(c.Segments) > 0 {
 		c.MaxSegmentId = c.Segments[len(c.Segments)-1].SegmentConfig.SegmentId + 1
 		c.Dim = c.Segments[0].SegmentConfig.Dim
 	}
+	return c.setState(CollectionStateLoaded, nil)
+}
+
+// Unload dumps the collection and releases its indexes and metadata from memory.
+//
+// The collection stays in VqliteCollectionList in unloaded state and is loaded again
+// on the next access.
+//
+// Returns:
+// - error: An error if the collection is not loaded or a segment is training.
+func (c *Collection) Unload() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.IsLoaded() {
+		return vqerrors.NotReady("collection [%s] is not loaded", c.Name())
+	}
+	for _, seg := range c.Segments {
+		if seg.IsTraining() {
+			return vqerrors.NotReady("collection [%s] segment %d is training", c.Name(), seg.SegmentConfig.SegmentId)
+		}
+	}
+
+	c.Dump()
+	c.DumpIndex()
 
+	releaseSegments(c.Segments)
+	c.Segments = make([]*Segment, 0)
+	c.MaxSegmentId = 0
+	_ = c.setState(CollectionStateUnloaded, nil)
+	log.Info().Msgf("unload collection [%s] success", c.Name())
+	return nil
 }
 
 func (c *Collection) LoadSegments(segmentsDirs []os.DirEntry) {
+	segments, err := c.loadSegments(segmentsDirs)
+	if err != nil {
+		log.Error().Err(err).Msg("load collection error")
+		return
+	}
+	c.Segments = segments
+}
+
+// loadSegmentsFromWorkDir loads all segments in the collection dir as new segments,
+// without touching the segments of the collection.
+func (c *Collection) loadSegmentsFromWorkDir() ([]*Segment, error) {
+	segmentsDirs, err := os.ReadDir(c.CollectionWorkDir)
+	if err != nil {
+		return nil, err
+	}
+	segmentsDirs = utils.FilterValidSegmentDirs(segmentsDirs)
+	utils.SortFileNameAscend(segmentsDirs)
+	return c.loadSegments(segmentsDirs)
+}
+
+// swapSegments replaces the segments of the collection and returns the old ones,
+// the caller must hold the collection lock.
+func (c *Collection) swapSegments(newSegments []*Segment) []*Segment {
+	oldSegments := c.Segments
+	c.Segments = newSegments
+	c.MaxSegmentId = 0
+	if len(newSegments) > 0 {
+		c.MaxSegmentId = newSegments[len(newSegments)-1].SegmentConfig.SegmentId + 1
+		c.Dim = newSegments[0].SegmentConfig.Dim
+	}
+	return oldSegments
+}
+
+// releaseSegments destroys the indexes of segments which are no longer used.
+func releaseSegments(segments []*Segment) {
+	for _, seg := range segments {
+		if seg.SegmentIndex.VIndexC != nil {
+			_ = seg.DropIndex()
+		}
+	}
+}
+
+func (c *Collection) loadSegments(segmentsDirs []os.DirEntry) ([]*Segment, error) {
 	tempSegments := make([]*Segment, len(segmentsDirs))
 
 	eg := &errgroup.Group{}
@@ -529,7 +983,7 @@ func (c *Collection) LoadSegments(segmentsDirs []os.DirEntry) {
 
 			segmentWorkDir := utils.Join(c.CollectionWorkDir, segmentDir.Name())
 			fmt.Println("load segment", segmentId, segmentWorkDir)
-			seg, err := NewSegment(segmentId, segmentWorkDir, 0)
+			seg, err := NewSegment(segmentId, segmentWorkDir, 0, scann.IndexParams{})
 			seg.Load()
 			if err == nil {
 				tempSegments[i] = seg
@@ -538,10 +992,9 @@ func (c *Collection) LoadSegments(segmentsDirs []os.DirEntry) {
 		})
 	}
 	if err := eg.Wait(); err != nil {
-		log.Error().Err(err).Msg("load collection error")
-		return
+		return nil, err
 	}
-	c.Segments = tempSegments
+	return tempSegments, nil
 }
 
 func (c *Collection) CheckAndLoadNewIndexSegments() {
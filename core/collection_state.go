@@ -0,0 +1,194 @@
+package core
+
+import (
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/atomic"
+	"sync"
+	"time"
+	vqerrors "vqlite/errors"
+)
+
+const (
+	CollectionStateUnloaded = "unloaded"
+	CollectionStateLoading  = "loading"
+	CollectionStateLoaded   = "loaded"
+	CollectionStateFailed   = "failed"
+)
+
+// collectionStateTransitions lists the states a collection can move to from each state.
+//
+//	unloaded -> loading -> loaded -> unloaded
+//	               |
+//	               +----> failed -> loading
+//
+// A new collection moves from unloaded to loaded directly, since there is nothing to load.
+var collectionStateTransitions = map[string][]string{
+	CollectionStateUnloaded: {CollectionStateLoading, CollectionStateLoaded},
+	CollectionStateLoading:  {CollectionStateLoading, CollectionStateLoaded, CollectionStateFailed},
+	CollectionStateLoaded:   {CollectionStateLoaded, CollectionStateUnloaded},
+	CollectionStateFailed:   {CollectionStateLoading, CollectionStateUnloaded},
+}
+
+// defaultRetryAfter is the Retry-After in seconds for a collection which was never loaded before.
+const defaultRetryAfter = 5
+
+// CollectionNotReadyError is returned for requests to a collection which is not loaded yet.
+type CollectionNotReadyError struct {
+	CollectionName string
+	State          string
+	LastError      string
+	RetryAfter     int // seconds
+}
+
+func (e *CollectionNotReadyError) ErrorCode() vqerrors.Code {
+	return vqerrors.CodeNotReady
+}
+
+func (e *CollectionNotReadyError) Error() string {
+	if e.LastError != "" {
+		return fmt.Sprintf("collection [%s] is %s, last error: %s", e.CollectionName, e.State, e.LastError)
+	}
+	return fmt.Sprintf("collection [%s] is %s", e.CollectionName, e.State)
+}
+
+// collectionLifecycle keeps the load state of a collection with the time of every change,
+// the last access time for the idle and LRU unload policy and the result of the last flush.
+type collectionLifecycle struct {
+	lock           sync.RWMutex
+	state          string
+	stateChangedAt time.Time
+	loadStartedAt  time.Time
+	loadedAt       time.Time
+	loadDuration   time.Duration
+	lastError      string
+	lastErrorAt    time.Time
+	lastAccess     atomic.Int64
+	lastFlushAt    time.Time
+	lastFlushError string
+}
+
+func (c *Collection) State() string {
+	c.lifecycle.lock.RLock()
+	defer c.lifecycle.lock.RUnlock()
+	return c.lifecycle.state
+}
+
+func (c *Collection) IsLoaded() bool {
+	return c.State() == CollectionStateLoaded
+}
+
+// setState moves the collection to state, err is recorded as last error when the load failed.
+// It returns an error if the transition is not allowed, the state is unchanged then.
+func (c *Collection) setState(state string, err error) error {
+	c.lifecycle.lock.Lock()
+	defer c.lifecycle.lock.Unlock()
+	return c.setStateLocked(state, err)
+}
+
+func (c *Collection) setStateLocked(state string, err error) error {
+	lc := &c.lifecycle
+	now := time.Now()
+	if lc.state != "" {
+		allowed := false
+		for _, to := range collectionStateTransitions[lc.state] {
+			if to == state {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			transitionErr := vqerrors.Internal("collection [%s] can not move from %s to %s", c.Name(), lc.state, state)
+			log.Error().Err(transitionErr).Msg("collection state error")
+			return transitionErr
+		}
+	}
+	if lc.state == state {
+		return nil
+	}
+
+	switch state {
+	case CollectionStateLoading:
+		lc.loadStartedAt = now
+	case CollectionStateLoaded:
+		lc.loadedAt = now
+		if lc.state == CollectionStateLoading {
+			lc.loadDuration = now.Sub(lc.loadStartedAt)
+		}
+	case CollectionStateFailed:
+		if err != nil {
+			lc.lastError = err.Error()
+			lc.lastErrorAt = now
+		}
+	}
+	log.Info().Msgf("collection [%s] state %s -> %s", c.Name(), lc.state, state)
+	lc.state = state
+	lc.stateChangedAt = now
+	return nil
+}
+
+// startLoading moves an unloaded or failed collection to loading,
+// it returns false if the collection is loading or loaded already.
+func (c *Collection) startLoading() bool {
+	c.lifecycle.lock.Lock()
+	defer c.lifecycle.lock.Unlock()
+	if c.lifecycle.state != CollectionStateUnloaded && c.lifecycle.state != CollectionStateFailed {
+		return false
+	}
+	return c.setStateLocked(CollectionStateLoading, nil) == nil
+}
+
+// notReadyError describes why the collection can not serve requests and when to retry.
+func (c *Collection) notReadyError() *CollectionNotReadyError {
+	c.lifecycle.lock.RLock()
+	defer c.lifecycle.lock.RUnlock()
+
+	retryAfter := defaultRetryAfter
+	// expect the load to take as long as the last one
+	if c.lifecycle.loadDuration > 0 {
+		remaining := c.lifecycle.loadDuration - time.Since(c.lifecycle.loadStartedAt)
+		retryAfter = int(remaining.Seconds()) + 1
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+	}
+	return &CollectionNotReadyError{
+		CollectionName: c.Name(),
+		State:          c.lifecycle.state,
+		LastError:      c.lifecycle.lastError,
+		RetryAfter:     retryAfter,
+	}
+}
+
+// LoadStatus returns the load state of the collection with timestamps and the last load error.
+func (c *Collection) LoadStatus() CollectionLoadStatus {
+	c.lifecycle.lock.RLock()
+	defer c.lifecycle.lock.RUnlock()
+	lc := &c.lifecycle
+	return CollectionLoadStatus{
+		State:          lc.state,
+		Ready:          lc.state == CollectionStateLoaded,
+		StateChangedAt: unixOrZero(lc.stateChangedAt),
+		LoadStartedAt:  unixOrZero(lc.loadStartedAt),
+		LoadedAt:       unixOrZero(lc.loadedAt),
+		LoadDuration:   lc.loadDuration.Milliseconds(),
+		LastError:      lc.lastError,
+		LastErrorAt:    unixOrZero(lc.lastErrorAt),
+	}
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// Touch records an access of the collection for the idle and LRU unload policy.
+func (c *Collection) Touch() {
+	c.lifecycle.lastAccess.Store(time.Now().UnixNano())
+}
+
+func (c *Collection) LastAccess() time.Time {
+	return time.Unix(0, c.lifecycle.lastAccess.Load())
+}
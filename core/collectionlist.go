@@ -8,16 +8,18 @@ var VqliteCollectionList CollectionList
 
 type CollectionList struct {
 	Collections map[string]*Collection
+	Aliases     map[string]string // alias -> collection name
 	lock        sync.RWMutex
 }
 
 func init() {
 	VqliteCollectionList.Collections = make(map[string]*Collection)
+	VqliteCollectionList.Aliases = make(map[string]string)
 }
 
 func (t *CollectionList) Add(collection *Collection) {
 	t.lock.Lock()
-	t.Collections[collection.Name] = collection
+	t.Collections[collection.Name()] = collection
 	t.lock.Unlock()
 }
 
@@ -34,6 +36,22 @@ func (t *CollectionList) Delete(name string) {
 	t.lock.Unlock()
 }
 
+// Rename moves the collection to newName and repoints its aliases in one step.
+func (t *CollectionList) Rename(name string, newName string) {
+	t.lock.Lock()
+	collection, ok := t.Collections[name]
+	if ok {
+		delete(t.Collections, name)
+		t.Collections[newName] = collection
+	}
+	for alias, collectionName := range t.Aliases {
+		if collectionName == name {
+			t.Aliases[alias] = newName
+		}
+	}
+	t.lock.Unlock()
+}
+
 func (t *CollectionList) Len() int {
 	t.lock.RLock()
 	n := len(t.Collections)
@@ -50,3 +68,36 @@ func (t *CollectionList) List() []*Collection {
 	t.lock.RUnlock()
 	return indexes
 }
+
+func (t *CollectionList) SetAlias(alias string, name string) {
+	t.lock.Lock()
+	t.Aliases[alias] = name
+	t.lock.Unlock()
+}
+
+func (t *CollectionList) DeleteAlias(alias string) {
+	t.lock.Lock()
+	delete(t.Aliases, alias)
+	t.lock.Unlock()
+}
+
+// Resolve returns the collection name of alias, or name itself if it is not an alias.
+func (t *CollectionList) Resolve(name string) string {
+	t.lock.RLock()
+	collectionName, ok := t.Aliases[name]
+	t.lock.RUnlock()
+	if ok {
+		return collectionName
+	}
+	return name
+}
+
+func (t *CollectionList) ListAliases() map[string]string {
+	t.lock.RLock()
+	aliases := make(map[string]string, len(t.Aliases))
+	for alias, name := range t.Aliases {
+		aliases[alias] = name
+	}
+	t.lock.RUnlock()
+	return aliases
+}
@@ -1,10 +1,16 @@
 package core
 
 import (
-	"fmt"
+	"context"
+	"github.com/rs/zerolog/log"
 	"os"
 	"runtime"
+	"strings"
+	"time"
 	"vqlite/config"
+	scann "vqlite/engine/go-scann"
+	"vqlite/engine/hnsw"
+	vqerrors "vqlite/errors"
 	"vqlite/utils"
 )
 
@@ -12,7 +18,8 @@ import (
 //	LoadCollections()
 //}
 
-func Statistics() *VQLiteStatistics {
+// Statistics returns the statistics of the collections allow returns true for, all of them for a nil allow.
+func Statistics(allow func(collectionName string) bool) *VQLiteStatistics {
 
 	vqliteStatistics := &VQLiteStatistics{
 		Collections:     make([]CollectionStatistics, 0),
@@ -20,7 +27,10 @@ func Statistics() *VQLiteStatistics {
 		TotalIndexSize:  0,
 		DocCount:        0,
 	}
-	for _, col := range VqliteCollectionList.Collections {
+	for _, col := range VqliteCollectionList.List() {
+		if allow != nil && !allow(col.Name()) {
+			continue
+		}
 		collectionStatistics := col.Statistics()
 		vqliteStatistics.Collections = append(vqliteStatistics.Collections, *collectionStatistics)
 		vqliteStatistics.CollectionCount += 1
@@ -30,29 +40,81 @@ func Statistics() *VQLiteStatistics {
 	return vqliteStatistics
 }
 
-func SearchCollection(collectionName string, vecs [][]float32, opt QueryOpt) ([][]SearchResult, error) {
-
-	collection, ok := VqliteCollectionList.Get(collectionName)
-	if !ok {
-		err := CheckCollection(collectionName)
-		if err != nil {
-			return nil, err
-		}
+// GetLoadedCollection returns the collection if it is loaded, an unloaded or failed collection is
+// loaded in background and a *CollectionNotReadyError is returned until it is loaded.
+func GetLoadedCollection(collectionName string) (*Collection, error) {
+	collection, err := GetCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	collection.Touch()
+	if collection.IsLoaded() {
+		return collection, nil
+	}
+	if collection.startLoading() {
 		go func() {
-			err := LoadCollection(collectionName)
+			err := collection.Load()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "load collection [%s] failed, err: %s", collectionName, err.Error())
+				log.Error().Err(err).Msgf("load collection [%s] failed", collectionName)
 				return
 			}
+			EnforceCollectionMemoryBudget()
 		}()
-		return nil, fmt.Errorf("collection [%s] is loding", collectionName)
 	}
+	return nil, collection.notReadyError()
+}
+
+// GetCollection returns the collection without loading it, a collection dir which is not
+// in VqliteCollectionList yet is added in unloaded state.
+func GetCollection(collectionName string) (*Collection, error) {
+	collection, ok := VqliteCollectionList.Get(collectionName)
+	if ok {
+		return collection, nil
+	}
+	if err := CheckCollection(collectionName); err != nil {
+		return nil, err
+	}
+	collection, err := NewCollection(collectionName, 0)
+	if err != nil {
+		if collection, ok = VqliteCollectionList.Get(collectionName); !ok {
+			return nil, err
+		}
+	}
+	return collection, nil
+}
 
-	flattenedVectors := utils.FlattenFloat32Slice(vecs)
-	CheckSearchOpt(&opt)
-	return collection.Search(flattenedVectors, opt)
+func SearchCollection(ctx context.Context, collectionName string, vecs [][]float32, opt QueryOpt) (*SearchResponse, error) {
+	// check before the vectors are copied into one slice
+	if err := checkQueryLimits(len(vecs), opt); err != nil {
+		return nil, err
+	}
+	return SearchCollectionFlattened(ctx, collectionName, utils.FlattenFloat32Slice(vecs), opt)
 }
 
+// SearchCollectionFlattened searches the query vectors given back to back in one slice,
+// its length must be a multiple of the collection dim.
+func SearchCollectionFlattened(ctx context.Context, collectionName string, flattenedVectors []float32, opt QueryOpt) (*SearchResponse, error) {
+
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if collection.Dim <= 0 || len(flattenedVectors) == 0 || len(flattenedVectors)%collection.Dim != 0 {
+		return nil, vqerrors.InvalidArgument("vectors size %d does not match collection dim %d", len(flattenedVectors), collection.Dim)
+	}
+	if err := checkQueryLimits(len(flattenedVectors)/collection.Dim, opt); err != nil {
+		return nil, err
+	}
+
+	collection.CheckSearchOpt(&opt)
+	if err := checkResultLimits(len(flattenedVectors)/collection.Dim, opt.TopK); err != nil {
+		return nil, err
+	}
+	return collection.Search(ctx, flattenedVectors, opt)
+}
+
+// CheckSearchOpt fills the unset options of opt with the global defaults, nprobe is left unset
+// when the nprobe ratio is set, every segment then derives it from its nlist.
 func CheckSearchOpt(opt *QueryOpt) {
 	if opt.TopK == 0 {
 		opt.TopK = 30
@@ -60,36 +122,131 @@ func CheckSearchOpt(opt *QueryOpt) {
 	if opt.Timeout == 0 {
 		opt.Timeout = 60
 	}
-	if opt.NProbe == 0 {
+	if opt.NProbe == 0 && opt.NProbeRatio == 0 {
 		opt.NProbe = 128
 	}
 	if opt.Reorder == 0 {
 		opt.Reorder = 128
 	}
+	if opt.EfSearch == 0 {
+		opt.EfSearch = hnsw.DefaultEfSearch
+	}
 }
 
-func CreateCollection(collectionName string, dim int) (*Collection, error) {
+// CreateCollection creates a collection kept in req.Storage, an in-memory collection never touches
+// disk, is not loaded again after a restart and is dropped once not accessed for req.TTL seconds.
+func CreateCollection(req *CreateCollectionRequest) (*Collection, error) {
+	collectionName := req.Name
 	if collectionName == "" {
-		return nil, fmt.Errorf("collection name is empty")
+		return nil, vqerrors.InvalidArgument("collection name is empty")
 	}
-	if dim <= 0 {
-		return nil, fmt.Errorf("dim must be greater than 0")
+	if req.Dim <= 0 {
+		return nil, vqerrors.InvalidArgument("dim must be greater than 0")
+	}
+	if !scann.ValidStorage(req.Storage) {
+		return nil, vqerrors.InvalidArgument("unknown storage [%s], must be %s or %s", req.Storage, scann.StorageFile, scann.StorageMemory)
+	}
+	if req.Storage == "" {
+		req.Storage = scann.StorageFile
+	}
+	if !validIndexType(req.IndexType) {
+		return nil, vqerrors.InvalidArgument("unknown index_type [%s], must be %s, %s or %s", req.IndexType, scann.IndexTypeScaNN, scann.IndexTypeFAISS, hnsw.IndexType)
+	}
+	if req.IndexType == "" {
+		req.IndexType = scann.IndexTypeScaNN
+	}
+	if req.TTL < 0 || (req.TTL > 0 && req.Storage != scann.StorageMemory) {
+		return nil, vqerrors.InvalidArgument("ttl %d must not be negative and is only used by storage [%s]", req.TTL, scann.StorageMemory)
 	}
 	_, ok := VqliteCollectionList.Get(collectionName)
 	if ok {
-		return nil, fmt.Errorf("collection [%s] already exists", collectionName)
+		return nil, vqerrors.AlreadyExists("collection [%s] already exists", collectionName)
 	}
-	col, err := NewCollection(collectionName, dim)
+	// a collection on disk is not in the list until accessed with lazy load
+	if CheckCollection(collectionName) == nil {
+		return nil, vqerrors.AlreadyExists("collection [%s] already exists", collectionName)
+	}
+	if _, err := GetAlias(collectionName); err == nil {
+		return nil, vqerrors.AlreadyExists("collection [%s] conflicts with alias [%s]", collectionName, collectionName)
+	}
+	col, err := newCollection(collectionName, req.Dim, req.Storage, time.Duration(req.TTL)*time.Second)
 	if err != nil {
 		return nil, err
 	}
+	col.IndexType = req.IndexType
+	if err := col.setConfig(CollectionConfig{IndexType: req.IndexType}); err != nil {
+		VqliteCollectionList.Delete(collectionName)
+		return nil, err
+	}
+	// a new collection has nothing to load
+	_ = col.setState(CollectionStateLoaded, nil)
 	return col, nil
 }
 
+// checkCollectionName checks that name is the name of a collection dir directly under the data path,
+// hidden dirs like the snapshots and temp dirs of restores and clones are not collections.
+func checkCollectionName(collectionName string) error {
+	if collectionName == "" {
+		return vqerrors.InvalidArgument("collection name is empty")
+	}
+	if strings.HasPrefix(collectionName, ".") || strings.ContainsAny(collectionName, `/\`) {
+		return vqerrors.InvalidArgument("invalid collection name [%s]", collectionName)
+	}
+	return nil
+}
+
+// checkNewCollectionName checks that name can be used for a new collection dir.
+func checkNewCollectionName(collectionName string) error {
+	if err := checkCollectionName(collectionName); err != nil {
+		return err
+	}
+	if _, ok := VqliteCollectionList.Get(collectionName); ok || CheckCollection(collectionName) == nil {
+		return vqerrors.AlreadyExists("collection [%s] already exists", collectionName)
+	}
+	if _, err := GetAlias(collectionName); err == nil {
+		return vqerrors.AlreadyExists("collection [%s] conflicts with alias [%s]", collectionName, collectionName)
+	}
+	return nil
+}
+
+func RenameCollection(collectionName string, newName string) (*Collection, error) {
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if err := collection.checkOnDisk("rename"); err != nil {
+		return nil, err
+	}
+	if err := checkNewCollectionName(newName); err != nil {
+		return nil, err
+	}
+	if err := collection.Rename(newName); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+func CloneCollection(collectionName string, newName string) (*Collection, error) {
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if err := collection.checkOnDisk("clone"); err != nil {
+		return nil, err
+	}
+	if err := checkNewCollectionName(newName); err != nil {
+		return nil, err
+	}
+	return collection.Clone(newName)
+}
+
 func DropCollection(collectionName string) error {
 	collection, ok := VqliteCollectionList.Get(collectionName)
 	if !ok {
-		return fmt.Errorf("collection [%s] not exists", collectionName)
+		return vqerrors.NotFound("collection [%s] not exists", collectionName)
+	}
+	if aliases := GetCollectionAliases(collectionName); len(aliases) > 0 {
+		return vqerrors.InvalidArgument("collection [%s] is used by aliases %v", collectionName, aliases)
 	}
 	err := collection.Drop()
 	if err != nil {
@@ -99,80 +256,87 @@ func DropCollection(collectionName string) error {
 }
 
 func AddDocument(collectionName string, doc *AddDocumentRequest) error {
-	collection, ok := VqliteCollectionList.Get(collectionName)
-	if !ok {
-		return fmt.Errorf("collection [%s] not exists", collectionName)
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return err
 	}
 	if doc.Vqid == "" {
-		return fmt.Errorf("vqid is empty")
+		return vqerrors.InvalidArgument("vqid is empty")
 	}
 	if len(doc.Vectors) == 0 {
-		return fmt.Errorf("vectors is empty")
+		return vqerrors.InvalidArgument("vectors is empty")
 	}
-	collection.AddDocument(doc)
-	return nil
+	if err := checkDocumentLimits(doc); err != nil {
+		return err
+	}
+	return collection.AddDocument(doc)
 }
 
 func BatchAddDocuments(collectionName string, documents *BatchAddDocumentsRequest) error {
-	collection, ok := VqliteCollectionList.Get(collectionName)
-	if !ok {
-		return fmt.Errorf("collection [%s] not exists", collectionName)
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return err
 	}
 	if len(documents.Documents) == 0 {
-		return fmt.Errorf("documents is empty")
+		return vqerrors.InvalidArgument("documents is empty")
+	}
+	if err := checkBatchLimits(documents); err != nil {
+		return err
 	}
 
-	collection.BatchAddDocuments(documents)
-	return nil
+	return collection.BatchAddDocuments(documents)
 }
 
 func DeleteDocument(collectionName string, vqid string) (int, error) {
 
-	collection, ok := VqliteCollectionList.Get(collectionName)
-	if !ok {
-		return 0, fmt.Errorf("collection [%s] not exists", collectionName)
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return 0, err
 	}
 	if vqid == "" {
-		return 0, fmt.Errorf("vqid is empty")
+		return 0, vqerrors.InvalidArgument("vqid is empty")
 	}
-	deletedCount := collection.DeleteDocument(vqid)
-	return deletedCount, nil
+	return collection.DeleteDocument(vqid)
 }
 
 func UpdateDocumentMetadata(collectionName string, doc *UpdateDocumentMetadataRequest) (int, error) {
-	collection, ok := VqliteCollectionList.Get(collectionName)
-	if !ok {
-		return 0, fmt.Errorf("collection [%s] not exists", collectionName)
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return 0, err
 	}
 	if doc.Vqid == "" {
-		return 0, fmt.Errorf("vqid is empty")
+		return 0, vqerrors.InvalidArgument("vqid is empty")
 	}
-	updatedCount := collection.UpdateDocumentMetadata(doc)
-	return updatedCount, nil
+	return collection.UpdateDocumentMetadata(doc)
 }
 
 func GetDocumentMetadata(collectionName string, vqid string, checkDuplicate bool) ([]DocumentMetadataResult, error) {
-	collection, ok := VqliteCollectionList.Get(collectionName)
-	if !ok {
-		return nil, fmt.Errorf("collection [%s] not exists", collectionName)
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return nil, err
 	}
 	return collection.GetDocumentMetadata(vqid, checkDuplicate), nil
 }
 
 func DumpCollection(collectionName string) error {
 
-	collection, ok := VqliteCollectionList.Get(collectionName)
-	if !ok {
-		return fmt.Errorf("collection [%s] not exists", collectionName)
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return err
 	}
-	collection.Dump()
-	return nil
+	if err := collection.checkOnDisk("dump"); err != nil {
+		return err
+	}
+	return collection.Dump()
 }
 func DumpCollectionMetadata(collectionName string) error {
 
-	collection, ok := VqliteCollectionList.Get(collectionName)
-	if !ok {
-		return fmt.Errorf("collection [%s] not exists", collectionName)
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return err
+	}
+	if err := collection.checkOnDisk("dump"); err != nil {
+		return err
 	}
 	collection.DumpMetadata()
 	return nil
@@ -180,21 +344,24 @@ func DumpCollectionMetadata(collectionName string) error {
 
 func DumpCollectionIndex(collectionName string) error {
 
-	collection, ok := VqliteCollectionList.Get(collectionName)
-	if !ok {
-		return fmt.Errorf("collection [%s] not exists", collectionName)
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return err
+	}
+	if err := collection.checkOnDisk("dump"); err != nil {
+		return err
 	}
 	collection.DumpIndex()
 	return nil
 }
 
-func TrainCollection(collectionName string, numThreads int, ignoreCheck bool) error {
+func TrainCollection(collectionName string, numThreads int, ignoreCheck bool, opt TrainOpt) error {
 
-	collection, ok := VqliteCollectionList.Get(collectionName)
-	if !ok {
-		return fmt.Errorf("collection [%s] not exists", collectionName)
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return err
 	}
-	err := collection.Train(numThreads, ignoreCheck) // train index and dump to disk
+	err = collection.Train(numThreads, ignoreCheck, opt) // train index and dump to disk
 	if err != nil {
 		return err
 	}
@@ -203,45 +370,76 @@ func TrainCollection(collectionName string, numThreads int, ignoreCheck bool) er
 }
 
 func CheckCollection(collectionName string) error {
-
+	if err := checkCollectionName(collectionName); err != nil {
+		return err
+	}
 	dataPath := config.GlobalConfig.ServiceConfig.DataPath
 	collectionPath := utils.Join(dataPath, collectionName)
 	if !utils.Exists(collectionPath) {
-		return fmt.Errorf("collection [%s] not exists", collectionName)
+		return vqerrors.NotFound("collection [%s] not exists", collectionName)
 	}
 	return nil
 }
 func LoadCollection(collectionName string) error {
 	col, ok := VqliteCollectionList.Get(collectionName)
 	// if collection not exist, create new collection , else load last segment.
-	fmt.Println("load collection", collectionName, ok)
+	log.Info().Msgf("load collection [%s], exists: %v", collectionName, ok)
 	if !ok {
 		newCol, err := NewCollection(collectionName, 0)
-		fmt.Println("load collection NewCollection", collectionName, err)
 		if err != nil {
 			return err
 		}
-		newCol.Load()
+		if err := newCol.Load(); err != nil {
+			return err
+		}
+	} else if !col.IsLoaded() {
+		if err := col.Load(); err != nil {
+			return err
+		}
 	} else {
 		col.CheckAndLoadNewIndexSegments()
 	}
+	EnforceCollectionMemoryBudget()
+	return nil
+}
+
+func UnloadCollection(collectionName string) error {
+	collection, ok := VqliteCollectionList.Get(collectionName)
+	if !ok {
+		return vqerrors.NotFound("collection [%s] not exists", collectionName)
+	}
+	if err := collection.checkOnDisk("unload"); err != nil {
+		return err
+	}
+	if err := collection.Unload(); err != nil {
+		return err
+	}
+	runtime.GC() // force gc
 	return nil
 }
 
+// LoadAllCollections adds all collections in the data path to VqliteCollectionList,
+// they are loaded unless lazyLoad is set, then they are loaded on first access.
+// In-memory collections have no dir, so they are gone after a restart.
 func LoadAllCollections() {
+	LoadAliases()
 	dataPath := config.GlobalConfig.ServiceConfig.DataPath
 	collectionNames, err := os.ReadDir(dataPath)
 	if err != nil {
 		return
 	}
 	for _, collectionName := range collectionNames {
-		if collectionName.IsDir() {
+		// hidden dirs are used for temp files, e.g. snapshot restore
+		if collectionName.IsDir() && !strings.HasPrefix(collectionName.Name(), ".") {
 			col, err := NewCollection(collectionName.Name(), 0)
 			if err != nil {
 				continue
 			}
-			col.Load()
+			if config.GlobalConfig.ServiceConfig.LazyLoad {
+				continue
+			}
+			_ = col.Load()
 		}
 	}
-
+	EnforceCollectionMemoryBudget()
 }
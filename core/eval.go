@@ -0,0 +1,355 @@
+package core
+
+import (
+	"container/heap"
+	"context"
+	"math/rand"
+	"sort"
+	"time"
+	scann "vqlite/engine/go-scann"
+	"vqlite/engine/hnsw"
+	vqerrors "vqlite/errors"
+	"vqlite/utils"
+)
+
+const (
+	defaultEvalSampleSize = 100
+	defaultEvalTopK       = 10
+)
+
+var (
+	defaultEvalNProbes  = []int{32, 64, 128, 256}
+	defaultEvalReorders = []int{64, 128, 256}
+)
+
+// EvalRequest evaluates the recall of the search. Vectors are the query vectors, without them
+// SampleSize vectors of the collection are sampled. Every NProbes and Reorders pair is searched,
+// or every EfSearches of a HNSW collection.
+type EvalRequest struct {
+	Vectors    [][]float32 `json:"vectors"`
+	SampleSize int         `json:"sample_size"`
+	TopK       int         `json:"topk"`
+	NProbes    []int       `json:"nprobes"`
+	Reorders   []int       `json:"reorders"`
+	EfSearches []int       `json:"ef_searches"`
+}
+
+// EvalResult is the mean recall@topk and the search latency of one QueryOpt.
+type EvalResult struct {
+	NProbe        int     `json:"nprobe"`
+	NProbeRatio   float64 `json:"nprobe_ratio,omitempty"`
+	Reorder       int     `json:"reorder"`
+	EfSearch      int     `json:"ef_search,omitempty"`
+	Recall        float64 `json:"recall"`
+	LatencyMeanMs float64 `json:"latency_mean_ms"`
+	LatencyP99Ms  float64 `json:"latency_p99_ms"`
+}
+
+type EvalResponse struct {
+	CollectionName string       `json:"collection_name"`
+	Queries        int          `json:"queries"`
+	TopK           int          `json:"topk"`
+	VectorCount    int          `json:"vector_count"`
+	ExactMs        float64      `json:"exact_ms"`
+	Results        []EvalResult `json:"results"`
+}
+
+// evalNeighbor is a vector of the exact neighbors, keyed like search results by vqid and tag.
+type evalNeighbor struct {
+	score float32
+	vqid  string
+	tag   int64
+}
+
+type evalKey struct {
+	vqid string
+	tag  int64
+}
+
+// neighborHeap is a min heap of the best neighbors found so far.
+type neighborHeap []evalNeighbor
+
+func (h neighborHeap) Len() int            { return len(h) }
+func (h neighborHeap) Less(i, j int) bool  { return h[i].score < h[j].score }
+func (h neighborHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *neighborHeap) Push(x interface{}) { *h = append(*h, x.(evalNeighbor)) }
+func (h *neighborHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// evaluation is a set of query vectors with their exact topk neighbors, searched with
+// every evaluated QueryOpt.
+type evaluation struct {
+	collection  *Collection
+	queries     [][]float32
+	exact       []map[evalKey]struct{}
+	vectorCount int
+	exactMs     float64
+}
+
+// EvalCollection computes the exact topk neighbors of the query vectors by brute force over the
+// datasets of every segment, then searches them with every QueryOpt of the grid and reports the
+// recall@topk and latency of each. Scores are inner products, like the index.
+func EvalCollection(ctx context.Context, collectionName string, req *EvalRequest) (*EvalResponse, error) {
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if req.TopK == 0 {
+		req.TopK = defaultEvalTopK
+	}
+	if req.SampleSize == 0 {
+		req.SampleSize = defaultEvalSampleSize
+	}
+	if len(req.NProbes) == 0 {
+		req.NProbes = defaultEvalNProbes
+	}
+	if len(req.Reorders) == 0 {
+		req.Reorders = defaultEvalReorders
+	}
+
+	eval, err := newEvaluation(ctx, collection, req.Vectors, req.SampleSize, req.TopK)
+	if err != nil {
+		return nil, err
+	}
+	resp := &EvalResponse{
+		CollectionName: collectionName,
+		Queries:        len(eval.queries),
+		TopK:           req.TopK,
+		VectorCount:    eval.vectorCount,
+		ExactMs:        eval.exactMs,
+		Results:        make([]EvalResult, 0, len(req.NProbes)*len(req.Reorders)),
+	}
+
+	if collection.Config().IndexType == hnsw.IndexType {
+		if len(req.EfSearches) == 0 {
+			req.EfSearches = tuneEfSearches(req.TopK, hnsw.IndexType)
+		}
+		for _, efSearch := range req.EfSearches {
+			opt := QueryOpt{TopK: req.TopK, EfSearch: efSearch}
+			if err := checkQueryLimits(1, opt); err != nil {
+				return nil, err
+			}
+			collection.CheckSearchOpt(&opt)
+			result, err := eval.run(ctx, opt)
+			if err != nil {
+				return nil, err
+			}
+			resp.Results = append(resp.Results, *result)
+		}
+		return resp, nil
+	}
+
+	for _, nprobe := range req.NProbes {
+		for _, reorder := range req.Reorders {
+			opt := QueryOpt{TopK: req.TopK, NProbe: nprobe, Reorder: reorder}
+			if err := checkQueryLimits(1, opt); err != nil {
+				return nil, err
+			}
+			collection.CheckSearchOpt(&opt)
+			result, err := eval.run(ctx, opt)
+			if err != nil {
+				return nil, err
+			}
+			resp.Results = append(resp.Results, *result)
+		}
+	}
+	return resp, nil
+}
+
+// newEvaluation takes vectors as the query vectors, or samples sampleSize vectors of the
+// collection without them, and computes their exact topk neighbors.
+func newEvaluation(ctx context.Context, collection *Collection, vectors [][]float32, sampleSize int, topk int) (*evaluation, error) {
+	if err := collection.checkOnDisk("evaluation"); err != nil {
+		return nil, err
+	}
+	if topk < 0 || sampleSize < 0 {
+		return nil, vqerrors.InvalidArgument("topk and sample_size must not be negative")
+	}
+	for _, vec := range vectors {
+		if len(vec) != collection.Dim {
+			return nil, vqerrors.InvalidArgument("vector size %d does not match collection dim %d", len(vec), collection.Dim)
+		}
+	}
+	queryCount := len(vectors)
+	if queryCount == 0 {
+		queryCount = sampleSize
+	}
+	if err := checkQueryLimits(queryCount, QueryOpt{TopK: topk}); err != nil {
+		return nil, err
+	}
+
+	segments := collection.GetSearchableSegments()
+	if len(segments) == 0 {
+		return nil, vqerrors.NotReady("collection [%s] has no searchable segment", collection.Name())
+	}
+	datasets := make(map[*Segment]*scann.Dataset, len(segments))
+	defer func() {
+		for _, dataset := range datasets {
+			dataset.Close()
+		}
+	}()
+	eval := &evaluation{collection: collection, queries: vectors}
+	for _, seg := range segments {
+		dataset, err := openSegmentDataset(seg)
+		if err != nil {
+			return nil, err
+		}
+		datasets[seg] = dataset
+		eval.vectorCount += dataset.Len()
+	}
+
+	if len(eval.queries) == 0 {
+		queries, err := sampleQueries(segments, datasets, eval.vectorCount, sampleSize)
+		if err != nil {
+			return nil, err
+		}
+		eval.queries = queries
+	}
+	if len(eval.queries) == 0 {
+		return nil, vqerrors.InvalidArgument("no query vectors")
+	}
+
+	start := time.Now()
+	exact, err := exactNeighbors(ctx, segments, datasets, eval.queries, topk)
+	if err != nil {
+		return nil, err
+	}
+	eval.exact = exact
+	eval.exactMs = durationMs(time.Since(start))
+	return eval, nil
+}
+
+// openSegmentDataset opens the dataset of seg, which must hold all vectors of the index.
+func openSegmentDataset(seg *Segment) (*scann.Dataset, error) {
+	stat, err := seg.Statistics()
+	if err != nil {
+		return nil, err
+	}
+	dataset, err := scann.OpenDataset(seg.SegmentIndex.VIndexC.WorkDir(), seg.SegmentConfig.Dim)
+	if err != nil {
+		return nil, err
+	}
+	if int64(dataset.Len()) != stat.VectorCount {
+		dataset.Close()
+		return nil, vqerrors.NotReady("dataset of segment [%d] has %d of %d vectors, dump the collection first",
+			seg.SegmentConfig.SegmentId, dataset.Len(), stat.VectorCount)
+	}
+	return dataset, nil
+}
+
+// sampleQueries picks sampleSize random vectors of all segments.
+func sampleQueries(segments []*Segment, datasets map[*Segment]*scann.Dataset, vectorCount int, sampleSize int) ([][]float32, error) {
+	if sampleSize > vectorCount {
+		sampleSize = vectorCount
+	}
+	queries := make([][]float32, 0, sampleSize)
+	for _, i := range rand.Perm(vectorCount)[:sampleSize] {
+		for _, seg := range segments {
+			dataset := datasets[seg]
+			if i >= dataset.Len() {
+				i -= dataset.Len()
+				continue
+			}
+			vec, err := dataset.Vector(i)
+			if err != nil {
+				return nil, err
+			}
+			queries = append(queries, vec)
+			break
+		}
+	}
+	return queries, nil
+}
+
+// exactNeighbors returns the topk neighbors of every query by scanning all vectors once,
+// vectors of deleted documents are skipped since search drops them as well.
+func exactNeighbors(ctx context.Context, segments []*Segment, datasets map[*Segment]*scann.Dataset, queries [][]float32, topk int) ([]map[evalKey]struct{}, error) {
+	heaps := make([]neighborHeap, len(queries))
+	for _, seg := range segments {
+		err := datasets[seg].Scan(func(vid int64, vec []float32) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			docId, tag := utils.DecodeVectorId(vid)
+			document := seg.SegmentMetadata.GetByid(int(docId))
+			if document == nil {
+				return nil
+			}
+			for q, query := range queries {
+				score := innerProduct(query, vec)
+				if len(heaps[q]) < topk {
+					heap.Push(&heaps[q], evalNeighbor{score: score, vqid: document.Vqid, tag: tag})
+				} else if score > heaps[q][0].score {
+					heaps[q][0] = evalNeighbor{score: score, vqid: document.Vqid, tag: tag}
+					heap.Fix(&heaps[q], 0)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, vqerrors.Wrap(vqerrors.CodeOf(err), err, "scan dataset of segment [%d] error", seg.SegmentConfig.SegmentId)
+		}
+	}
+
+	exact := make([]map[evalKey]struct{}, len(queries))
+	for q, h := range heaps {
+		exact[q] = make(map[evalKey]struct{}, len(h))
+		for _, neighbor := range h {
+			exact[q][evalKey{vqid: neighbor.vqid, tag: neighbor.tag}] = struct{}{}
+		}
+	}
+	return exact, nil
+}
+
+// run searches every query alone with opt, so the latency is the one of a single query.
+func (e *evaluation) run(ctx context.Context, opt QueryOpt) (*EvalResult, error) {
+	queries, exact := e.queries, e.exact
+	latencies := make([]float64, 0, len(queries))
+	recallSum := 0.0
+	for q, query := range queries {
+		start := time.Now()
+		resp, err := e.collection.Search(ctx, query, opt)
+		if err != nil {
+			return nil, err
+		}
+		latencies = append(latencies, durationMs(time.Since(start)))
+		if len(exact[q]) == 0 {
+			recallSum += 1
+			continue
+		}
+		hits := 0
+		for _, result := range resp.Results[0] {
+			if _, ok := exact[q][evalKey{vqid: result.Vqid, tag: result.Tag}]; ok {
+				hits++
+			}
+		}
+		recallSum += float64(hits) / float64(len(exact[q]))
+	}
+
+	sort.Float64s(latencies)
+	latencySum := 0.0
+	for _, latency := range latencies {
+		latencySum += latency
+	}
+	return &EvalResult{
+		NProbe:        opt.NProbe,
+		NProbeRatio:   opt.NProbeRatio,
+		Reorder:       opt.Reorder,
+		EfSearch:      opt.EfSearch,
+		Recall:        recallSum / float64(len(queries)),
+		LatencyMeanMs: latencySum / float64(len(latencies)),
+		LatencyP99Ms:  latencies[(len(latencies)*99)/100],
+	}, nil
+}
+
+func innerProduct(a []float32, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
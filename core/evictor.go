@@ -0,0 +1,114 @@
+package core
+
+import (
+	"github.com/rs/zerolog/log"
+	"sort"
+	"sync"
+	"time"
+	"vqlite/config"
+)
+
+var (
+	evictorOnce sync.Once
+	evictLock   sync.Mutex
+)
+
+// StartCollectionEvictor starts the background loop dropping expired in-memory collections and
+// unloading idle collections and least recently used collections above the memory budget.
+func StartCollectionEvictor() {
+	evictorOnce.Do(func() {
+		interval := time.Duration(config.GlobalConfig.ServiceConfig.CollectionEvictInterval) * time.Second
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				DropExpiredCollections()
+				UnloadIdleCollections()
+				EnforceCollectionMemoryBudget()
+			}
+		}()
+	})
+}
+
+// UnloadIdleCollections unloads the collections not accessed for collectionIdleTimeout seconds.
+func UnloadIdleCollections() {
+	idleTimeout := time.Duration(config.GlobalConfig.ServiceConfig.CollectionIdleTimeout) * time.Second
+	if idleTimeout <= 0 {
+		return
+	}
+	evictLock.Lock()
+	defer evictLock.Unlock()
+
+	for _, col := range VqliteCollectionList.List() {
+		// an in-memory collection can not be loaded again
+		if !col.IsLoaded() || col.InMemory() || time.Since(col.LastAccess()) < idleTimeout {
+			continue
+		}
+		if err := col.Unload(); err != nil {
+			log.Warn().Err(err).Msgf("unload idle collection [%s] error", col.Name())
+			continue
+		}
+		log.Info().Msgf("unload idle collection [%s], last access %v", col.Name(), col.LastAccess())
+	}
+}
+
+// EnforceCollectionMemoryBudget unloads the least recently used collections until the estimated
+// memory of all loaded collections is within collectionMemoryBudget.
+func EnforceCollectionMemoryBudget() {
+	budget := config.GlobalConfig.ServiceConfig.CollectionMemoryBudget * 1024 * 1024
+	if budget <= 0 {
+		return
+	}
+	evictLock.Lock()
+	defer evictLock.Unlock()
+
+	loadedCollections := make([]*Collection, 0)
+	memorySizes := make(map[string]int64)
+	var totalMemorySize int64
+	for _, col := range VqliteCollectionList.List() {
+		if !col.IsLoaded() {
+			continue
+		}
+		memorySize := col.Statistics().MemorySize
+		memorySizes[col.Name()] = memorySize
+		totalMemorySize += memorySize
+		// an in-memory collection counts for the budget, but can not be loaded again
+		if !col.InMemory() {
+			loadedCollections = append(loadedCollections, col)
+		}
+	}
+	if totalMemorySize <= budget || len(loadedCollections) == 0 {
+		return
+	}
+
+	sort.Slice(loadedCollections, func(i, j int) bool {
+		return loadedCollections[i].LastAccess().Before(loadedCollections[j].LastAccess())
+	})
+	// keep the most recently used collection loaded, even if it exceeds the budget alone
+	for _, col := range loadedCollections[:len(loadedCollections)-1] {
+		if totalMemorySize <= budget {
+			break
+		}
+		if err := col.Unload(); err != nil {
+			log.Warn().Err(err).Msgf("unload collection [%s] for memory budget error", col.Name())
+			continue
+		}
+		totalMemorySize -= memorySizes[col.Name()]
+		log.Info().Msgf("unload collection [%s] for memory budget, memory size %d, total %d, budget %d",
+			col.Name(), memorySizes[col.Name()], totalMemorySize, budget)
+	}
+}
+
+// DropExpiredCollections drops the in-memory collections not accessed for their TTL.
+func DropExpiredCollections() {
+	for _, col := range VqliteCollectionList.List() {
+		if !col.InMemory() || col.TTL <= 0 || time.Since(col.LastAccess()) < col.TTL {
+			continue
+		}
+		if err := DropCollection(col.Name()); err != nil {
+			log.Warn().Err(err).Msgf("drop expired collection [%s] error", col.Name())
+			continue
+		}
+		log.Info().Msgf("drop expired collection [%s], last access %v, ttl %v", col.Name(), col.LastAccess(), col.TTL)
+	}
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+	"vqlite/config"
+)
+
+var flusherOnce sync.Once
+
+// StartCollectionFlusher starts the background loop dumping the dirty segments of every
+// loaded collection each flushInterval seconds, it does nothing if flushInterval is 0.
+func StartCollectionFlusher() {
+	interval := time.Duration(config.GlobalConfig.ServiceConfig.FlushInterval) * time.Second
+	if interval <= 0 {
+		return
+	}
+	flusherOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				FlushDirtyCollections()
+			}
+		}()
+	})
+}
+
+// FlushDirtyCollections dumps the dirty segments of every loaded collection.
+func FlushDirtyCollections() {
+	for _, col := range VqliteCollectionList.List() {
+		flushedCount, err := col.Flush()
+		if err != nil {
+			log.Error().Err(err).Msgf("flush collection [%s] error", col.Name())
+			continue
+		}
+		if flushedCount > 0 {
+			log.Info().Msgf("flush collection [%s] success, segments: %d", col.Name(), flushedCount)
+		}
+	}
+}
+
+// Flush dumps the metadata and config of the segments changed since their last dump.
+//
+// Adding documents waits for the flush, deleting and updating documents may run alongside,
+// the segments they change stay dirty until the next flush.
+//
+// Returns:
+// - int: The number of flushed segments.
+// - error: The first error of the segments failed to dump, they stay dirty.
+func (c *Collection) Flush() (int, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if !c.IsLoaded() {
+		return 0, nil
+	}
+
+	flushedCount := 0
+	var firstErr error
+	for _, seg := range c.Segments {
+		if !seg.IsDirty() {
+			continue
+		}
+		if err := seg.Dump(); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		flushedCount += 1
+	}
+	if flushedCount > 0 || firstErr != nil {
+		c.recordFlush(firstErr)
+	}
+	return flushedCount, firstErr
+}
+
+// recordFlush keeps the time and error of the last flush, a successful flush clears the error.
+func (c *Collection) recordFlush(err error) {
+	c.lifecycle.lock.Lock()
+	defer c.lifecycle.lock.Unlock()
+	if err != nil {
+		c.lifecycle.lastFlushError = err.Error()
+		return
+	}
+	c.lifecycle.lastFlushAt = time.Now()
+	c.lifecycle.lastFlushError = ""
+}
+
+// LastFlush returns the time of the last successful flush and the error of the last failed one.
+func (c *Collection) LastFlush() (time.Time, string) {
+	c.lifecycle.lock.RLock()
+	defer c.lifecycle.lock.RUnlock()
+	return c.lifecycle.lastFlushAt, c.lifecycle.lastFlushError
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"vqlite/config"
+	"vqlite/engine/hnsw"
+)
+
+const testDim = 16
+
+func setTestConfig(t *testing.T) {
+	t.Helper()
+	oldConfig := config.GlobalConfig
+	config.GlobalConfig = &config.Config{
+		ServiceConfig: config.ServiceConfig{
+			DataPath:             t.TempDir(),
+			SegmentVectorMaxSize: 10000,
+		},
+		LimitConfig: config.LimitConfig{
+			MaxDocumentVectors: 1024,
+			MaxBatchDocuments:  10000,
+			MaxQueryVectors:    1024,
+			MaxTopK:            10000,
+			MaxNProbe:          100000,
+			MaxReorder:         100000,
+			MaxEfSearch:        10000,
+			MaxQueryResults:    1000000,
+		},
+	}
+	t.Cleanup(func() { config.GlobalConfig = oldConfig })
+}
+
+func randomDocuments(rng *rand.Rand, n int) []AddDocumentRequest {
+	documents := make([]AddDocumentRequest, n)
+	for i := range documents {
+		vec := make([]float32, testDim)
+		for j := range vec {
+			vec[j] = float32(rng.NormFloat64())
+		}
+		documents[i] = AddDocumentRequest{Vqid: fmt.Sprintf("doc_%d", i), Vectors: [][]float32{vec}}
+	}
+	return documents
+}
+
+// TestHNSWDeleteAfterSnapshot deletes documents of a HNSW collection whose files are hard linked by a snapshot,
+// the tombstones must survive unsharing the files on the next dump and be found after loading it again.
+func TestHNSWDeleteAfterSnapshot(t *testing.T) {
+	setTestConfig(t)
+	const name = "hnsw_snapshot"
+	if _, err := CreateCollection(&CreateCollectionRequest{Name: name, Dim: testDim, IndexType: hnsw.IndexType}); err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+	t.Cleanup(func() { _ = DropCollection(name) })
+	documents := randomDocuments(rand.New(rand.NewSource(1)), 200)
+	if err := BatchAddDocuments(name, &BatchAddDocumentsRequest{Documents: documents}); err != nil {
+		t.Fatalf("add documents: %v", err)
+	}
+	if _, err := CreateCollectionSnapshot(name, "before_delete"); err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		if deleted, err := DeleteDocument(name, documents[i].Vqid); err != nil || deleted != 1 {
+			t.Fatalf("delete %s: deleted %d, %v", documents[i].Vqid, deleted, err)
+		}
+	}
+	if err := DumpCollection(name); err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	if err := UnloadCollection(name); err != nil {
+		t.Fatalf("unload: %v", err)
+	}
+	if err := LoadCollection(name); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	const topK = 10
+	for i := 0; i < 20; i++ {
+		response, err := SearchCollection(context.Background(), name, documents[i].Vectors, QueryOpt{TopK: topK, Debug: true})
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(response.Results[0]) != topK {
+			t.Fatalf("search returned %d results, want %d", len(response.Results[0]), topK)
+		}
+		// a deleted vector left in the graph is found by its own vector and dropped after the search
+		for _, segmentDebug := range response.Debug.Segments {
+			if segmentDebug.DroppedDeleted > 0 {
+				t.Fatalf("segment %d returned %d deleted vectors for %s", segmentDebug.SegmentId, segmentDebug.DroppedDeleted, documents[i].Vqid)
+			}
+		}
+		for _, result := range response.Results[0] {
+			if result.Vqid == documents[i].Vqid {
+				t.Fatalf("deleted document %s found", result.Vqid)
+			}
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package core
+
+import (
+	"github.com/rs/zerolog/log"
+	"math"
+	"sync"
+	"time"
+	"vqlite/config"
+	scann "vqlite/engine/go-scann"
+	vqerrors "vqlite/errors"
+)
+
+var autoTrainerOnce sync.Once
+
+// VectorStats sums the vectors a segment was fully trained on and the ones added since,
+// so the drift of new vectors is known without reading the dataset.
+type VectorStats struct {
+	TrainedSum   []float64
+	TrainedCount int64
+	DeltaSum     []float64
+	DeltaCount   int64
+}
+
+func (v *VectorStats) add(vectors [][]float32) {
+	for _, vec := range vectors {
+		if len(v.DeltaSum) != len(vec) {
+			v.DeltaSum = make([]float64, len(vec))
+		}
+		for i, x := range vec {
+			v.DeltaSum[i] += float64(x)
+		}
+		v.DeltaCount++
+	}
+}
+
+// fold moves delta, the added vectors a full training was started with, to the trained ones.
+func (v *VectorStats) fold(delta VectorStats) {
+	if len(v.TrainedSum) != len(delta.DeltaSum) {
+		v.TrainedSum = make([]float64, len(delta.DeltaSum))
+	}
+	for i, x := range delta.DeltaSum {
+		v.TrainedSum[i] += x
+		if i < len(v.DeltaSum) {
+			v.DeltaSum[i] -= x
+		}
+	}
+	v.TrainedCount += delta.DeltaCount
+	v.DeltaCount -= delta.DeltaCount
+}
+
+// drift returns the cosine distance of the mean of the added and trained vectors, 0 if either is unknown.
+func (v *VectorStats) drift() float64 {
+	if v.TrainedCount == 0 || v.DeltaCount == 0 || len(v.TrainedSum) != len(v.DeltaSum) {
+		return 0
+	}
+	var dot, trainedNorm, deltaNorm float64
+	for i := range v.TrainedSum {
+		dot += v.TrainedSum[i] * v.DeltaSum[i]
+		trainedNorm += v.TrainedSum[i] * v.TrainedSum[i]
+		deltaNorm += v.DeltaSum[i] * v.DeltaSum[i]
+	}
+	if trainedNorm == 0 || deltaNorm == 0 {
+		return 0
+	}
+	return 1 - dot/math.Sqrt(trainedNorm*deltaNorm)
+}
+
+// copy returns a deep copy, the sums are changed in place.
+func (v *VectorStats) copy() VectorStats {
+	return VectorStats{
+		TrainedSum:   append([]float64(nil), v.TrainedSum...),
+		TrainedCount: v.TrainedCount,
+		DeltaSum:     append([]float64(nil), v.DeltaSum...),
+		DeltaCount:   v.DeltaCount,
+	}
+}
+
+func (s *Segment) addVectorStats(vectors [][]float32) {
+	s.vectorStatsLock.Lock()
+	defer s.vectorStatsLock.Unlock()
+	s.SegmentConfig.VectorStats.add(vectors)
+}
+
+func (s *Segment) vectorStats() VectorStats {
+	s.vectorStatsLock.Lock()
+	defer s.vectorStatsLock.Unlock()
+	return s.SegmentConfig.VectorStats.copy()
+}
+
+// driftStats returns the ratio of vectors added since the last full training to the ones it trained on,
+// and the drift of their mean. Vectors not indexed yet always count as added, so segments trained
+// before the stats were kept are measured by them, without drift.
+func (s *Segment) driftStats(indexStatistics scann.IndexStatistics) (float64, float64) {
+	stats := s.vectorStats()
+	deltaCount := stats.DeltaCount
+	if unindexed := indexStatistics.VidSize - indexStatistics.IndexSize; unindexed > deltaCount {
+		deltaCount = unindexed
+	}
+	trainedCount := indexStatistics.VidSize - deltaCount
+	if trainedCount <= 0 {
+		return math.Inf(1), 0
+	}
+	return float64(deltaCount) / float64(trainedCount), stats.drift()
+}
+
+// chooseTrainType returns trainType, or when it is unset or default and incremental trainings are
+// enabled, add for a trained segment with a small delta and drift and new otherwise.
+func (s *Segment) chooseTrainType(trainType string, indexStatistics scann.IndexStatistics) (string, error) {
+	trained := indexStatistics.IndexSize > 0 && !indexStatistics.IsBrute
+	if trainType == scann.TrainTypeAdd && !trained {
+		return "", vqerrors.InvalidArgument("train_type [%s] needs a trained index, segment %d has none", trainType, s.SegmentConfig.SegmentId)
+	}
+	trainConfig := config.GlobalConfig.TrainConfig
+	if (trainType != "" && trainType != scann.TrainTypeDefault) || !trainConfig.IncrementalTrain {
+		return trainType, nil
+	}
+	if !trained {
+		return scann.TrainTypeNew, nil
+	}
+	delta, drift := s.driftStats(indexStatistics)
+	if delta > trainConfig.IncrementalMaxDelta || drift > trainConfig.IncrementalMaxDrift {
+		log.Info().Msgf("segment %d needs a full training, delta %.3f, drift %.4f", s.SegmentConfig.SegmentId, delta, drift)
+		return scann.TrainTypeNew, nil
+	}
+	return scann.TrainTypeAdd, nil
+}
+
+// StartIncrementalTrainer starts the background loop training every segment of the loaded collections
+// incrementally each autoTrainInterval seconds when it has new vectors and may be trained incrementally,
+// it does nothing if autoTrainInterval is 0 or incremental trainings are disabled.
+func StartIncrementalTrainer() {
+	trainConfig := config.GlobalConfig.TrainConfig
+	interval := time.Duration(trainConfig.AutoTrainInterval) * time.Second
+	if interval <= 0 || !trainConfig.IncrementalTrain {
+		return
+	}
+	autoTrainerOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				TrainCollectionsIncrementally()
+			}
+		}()
+	})
+}
+
+// TrainCollectionsIncrementally trains the segments of every loaded collection which may be trained incrementally.
+func TrainCollectionsIncrementally() {
+	for _, col := range VqliteCollectionList.List() {
+		if !col.IsLoaded() {
+			continue
+		}
+		trainedCount, err := col.TrainIncrementally()
+		if err != nil {
+			log.Error().Err(err).Msgf("incremental training of collection [%s] error", col.Name())
+			continue
+		}
+		if trainedCount > 0 {
+			log.Info().Msgf("incremental training of collection [%s] success, segments: %d", col.Name(), trainedCount)
+		}
+	}
+}
+
+// TrainIncrementally trains the segments with new vectors which may be trained incrementally,
+// segments due for a full training are left as they are.
+//
+// Returns:
+// - int: The number of trained segments.
+// - error: The first error of the segments failed to train.
+func (c *Collection) TrainIncrementally() (int, error) {
+	opt := TrainOpt{TrainType: scann.TrainTypeDefault}.withDefaults(c.Config().TrainOpt)
+	trainedCount := 0
+	var firstErr error
+	for _, seg := range c.GetSearchableSegments() {
+		stat, err := seg.Statistics()
+		if err != nil || seg.IsTraining() {
+			continue
+		}
+		indexStatistics := stat.IndexStatistics
+		if indexStatistics.VidSize == 0 || indexStatistics.IndexSize >= indexStatistics.VidSize {
+			continue
+		}
+		trainType, err := seg.chooseTrainType(scann.TrainTypeDefault, indexStatistics)
+		if err != nil || trainType != scann.TrainTypeAdd {
+			continue
+		}
+		trainParams := opt.trainParams()
+		trainParams.Type = trainType
+		trainParams.Nlist = 0
+		if err := seg.Train(0, opt.indexParams(), trainParams); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		trainTotal.WithLabelValues(c.Name(), trainType).Inc()
+		trainedCount++
+	}
+	return trainedCount, firstErr
+}
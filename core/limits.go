@@ -0,0 +1,67 @@
+package core
+
+import (
+	"vqlite/config"
+	vqerrors "vqlite/errors"
+)
+
+// checkDocumentLimits rejects documents with more vectors than maxDocumentVectors.
+func checkDocumentLimits(doc *AddDocumentRequest) error {
+	if maxVectors := config.GlobalConfig.LimitConfig.MaxDocumentVectors; len(doc.Vectors) > maxVectors {
+		return vqerrors.TooLarge("document [%s] has %d vectors, max is %d", doc.Vqid, len(doc.Vectors), maxVectors)
+	}
+	return nil
+}
+
+// checkBatchLimits rejects batches with more documents than maxBatchDocuments or too large documents.
+func checkBatchLimits(documents *BatchAddDocumentsRequest) error {
+	if maxDocuments := config.GlobalConfig.LimitConfig.MaxBatchDocuments; len(documents.Documents) > maxDocuments {
+		return vqerrors.TooLarge("batch has %d documents, max is %d", len(documents.Documents), maxDocuments)
+	}
+	for i := range documents.Documents {
+		if err := checkDocumentLimits(&documents.Documents[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkQueryLimits rejects searches with more query vectors than maxQueryVectors, and options out of range,
+// since the index allocates nq*topk results before searching.
+func checkQueryLimits(nq int, opt QueryOpt) error {
+	limitConfig := config.GlobalConfig.LimitConfig
+	if nq > limitConfig.MaxQueryVectors {
+		return vqerrors.TooLarge("search has %d query vectors, max is %d", nq, limitConfig.MaxQueryVectors)
+	}
+	if opt.TopK < 0 || opt.TopK > limitConfig.MaxTopK {
+		return vqerrors.InvalidArgument("topk %d must be in [0, %d], 0 uses the default", opt.TopK, limitConfig.MaxTopK)
+	}
+	if err := checkResultLimits(nq, opt.TopK); err != nil {
+		return err
+	}
+	if opt.NProbe < 0 || opt.NProbe > limitConfig.MaxNProbe {
+		return vqerrors.InvalidArgument("nprobe %d must be in [0, %d], 0 uses the default", opt.NProbe, limitConfig.MaxNProbe)
+	}
+	if opt.Reorder < 0 || opt.Reorder > limitConfig.MaxReorder {
+		return vqerrors.InvalidArgument("reorder %d must be in [0, %d], 0 uses the default", opt.Reorder, limitConfig.MaxReorder)
+	}
+	if opt.EfSearch < 0 || opt.EfSearch > limitConfig.MaxEfSearch {
+		return vqerrors.InvalidArgument("ef_search %d must be in [0, %d], 0 uses the default", opt.EfSearch, limitConfig.MaxEfSearch)
+	}
+	if opt.NProbeRatio < 0 || opt.NProbeRatio > 1 {
+		return vqerrors.InvalidArgument("nprobe_ratio %v must be in [0, 1], 0 uses the default", opt.NProbeRatio)
+	}
+	if opt.Timeout < 0 {
+		return vqerrors.InvalidArgument("timeout %d is negative", opt.Timeout)
+	}
+	return nil
+}
+
+// checkResultLimits rejects searches of more than maxQueryResults results, nq*topk,
+// it is checked again once the topk of the collection or the default filled an unset one.
+func checkResultLimits(nq int, topK int) error {
+	if maxResults := config.GlobalConfig.LimitConfig.MaxQueryResults; int64(nq)*int64(topK) > int64(maxResults) {
+		return vqerrors.TooLarge("search of %d query vectors with topk %d has more than %d results", nq, topK, maxResults)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package core
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	searchTimeoutsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vqlite_search_timeouts_total",
+		Help: "Searches with segments which did not answer before the timeout.",
+	}, []string{"collection"})
+
+	searchPartialTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vqlite_search_partial_total",
+		Help: "Searches which returned partial results without the failed segments.",
+	}, []string{"collection"})
+
+	searchCanceledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vqlite_search_canceled_total",
+		Help: "Searches canceled by the client before they finished.",
+	}, []string{"collection"})
+
+	trainTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vqlite_train_total",
+		Help: "Segment trainings by train type, add trainings are incremental.",
+	}, []string{"collection", "train_type"})
+)
@@ -0,0 +1,315 @@
+package core
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"time"
+	"vqlite/config"
+	scann "vqlite/engine/go-scann"
+	"vqlite/engine/hnsw"
+	vqerrors "vqlite/errors"
+	"vqlite/utils"
+)
+
+// CollectionConfigFileName is the file in the collection dir that keeps the collection config.
+const CollectionConfigFileName = "collection.gob"
+
+const defaultTuneTargetRecall = 0.95
+
+// CollectionConfig is the config of a collection, kept next to its segments so it moves
+// with renames, clones and snapshots.
+type CollectionConfig struct {
+	// SearchOpt fills the options a search leaves unset, before the global defaults
+	SearchOpt QueryOpt
+	// Tuning is the last auto tune which set SearchOpt, nil if never tuned
+	Tuning *SearchTuning
+	// TrainOpt fills the parameters a training leaves unset, before the engine defaults
+	TrainOpt TrainOpt
+	// IndexType of the segments, also recorded in every segment config, empty for collections
+	// created before it was kept, which take the one of their first segment
+	IndexType string
+}
+
+// SearchTuning is the recall and latency the stored search options reached when tuned.
+type SearchTuning struct {
+	TargetRecall  float64 `json:"target_recall"`
+	TopK          int     `json:"topk"`
+	Queries       int     `json:"queries"`
+	Recall        float64 `json:"recall"`
+	LatencyMeanMs float64 `json:"latency_mean_ms"`
+	LatencyP99Ms  float64 `json:"latency_p99_ms"`
+	TunedAt       int64   `json:"tuned_at"` // unix seconds
+}
+
+// TuneRequest tunes nprobe and reorder to reach TargetRecall at topk with the least latency,
+// on SampleSize sampled vectors of the collection.
+type TuneRequest struct {
+	TargetRecall float64 `json:"target_recall"`
+	SampleSize   int     `json:"sample_size"`
+	TopK         int     `json:"topk"`
+}
+
+// TuneResponse has every evaluated option, Applied is false when none reached the target recall
+// and the stored search options are unchanged then.
+type TuneResponse struct {
+	CollectionName string        `json:"collection_name"`
+	Applied        bool          `json:"applied"`
+	SearchOpt      QueryOpt      `json:"search_opt"`
+	Tuning         *SearchTuning `json:"tuning,omitempty"`
+	Results        []EvalResult  `json:"results"`
+}
+
+func (c *Collection) configFilePath() string {
+	return utils.Join(c.CollectionWorkDir, CollectionConfigFileName)
+}
+
+// Config returns a copy of the collection config.
+func (c *Collection) Config() CollectionConfig {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+	return c.config
+}
+
+// setConfig replaces the collection config and dumps it, an in-memory collection only keeps it in memory.
+func (c *Collection) setConfig(collectionConfig CollectionConfig) error {
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+	if c.InMemory() {
+		c.config = collectionConfig
+		return nil
+	}
+	if err := utils.Dump(collectionConfig, c.configFilePath()); err != nil {
+		log.Error().Err(err).Msgf("dump config of collection [%s] error", c.Name())
+		return err
+	}
+	c.config = collectionConfig
+	return nil
+}
+
+// loadConfig loads the collection config from the collection dir, a collection which was never
+// configured has none and gets the zero config. The index type of the collection is set from it.
+func (c *Collection) loadConfig() {
+	collectionConfig := CollectionConfig{}
+	filename := c.configFilePath()
+	if utils.Exists(filename) {
+		if err := utils.Load(&collectionConfig, filename); err != nil {
+			log.Error().Err(err).Msgf("load config of collection [%s] error", c.Name())
+		}
+	}
+	if collectionConfig.IndexType == "" && len(c.Segments) > 0 {
+		collectionConfig.IndexType = c.Segments[0].SegmentConfig.IndexParams.IndexType
+	}
+	if collectionConfig.IndexType == "" {
+		collectionConfig.IndexType = scann.IndexTypeScaNN
+	}
+	c.IndexType = collectionConfig.IndexType
+	c.configLock.Lock()
+	c.config = collectionConfig
+	c.configLock.Unlock()
+}
+
+// CheckSearchOpt fills the unset options of opt with the stored search options of the collection,
+// then with the global defaults. A stored nprobe or nprobe ratio is only used when opt sets neither.
+func (c *Collection) CheckSearchOpt(opt *QueryOpt) {
+	stored := c.Config().SearchOpt
+	if opt.TopK == 0 {
+		opt.TopK = stored.TopK
+	}
+	if opt.Timeout == 0 {
+		opt.Timeout = stored.Timeout
+	}
+	if opt.NProbe == 0 && opt.NProbeRatio == 0 {
+		opt.NProbe = stored.NProbe
+		opt.NProbeRatio = stored.NProbeRatio
+	}
+	if opt.Reorder == 0 {
+		opt.Reorder = stored.Reorder
+	}
+	if opt.EfSearch == 0 {
+		opt.EfSearch = stored.EfSearch
+	}
+	opt.AllowPartial = opt.AllowPartial || stored.AllowPartial
+	CheckSearchOpt(opt)
+	if c.Config().IndexType == scann.IndexTypeFAISS {
+		// a FAISS index searches the probed partitions exactly, there is nothing to reorder
+		opt.Reorder = opt.TopK
+	}
+}
+
+// SetSearchOpt stores opt as the search options of the collection, zero options use the global defaults.
+// Storing options by hand drops the last tuning.
+func SetSearchOpt(collectionName string, opt QueryOpt) (*Collection, error) {
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkQueryLimits(0, opt); err != nil {
+		return nil, err
+	}
+	// debug is asked for by a search, never stored
+	opt.Debug = false
+	collectionConfig := collection.Config()
+	collectionConfig.SearchOpt = opt
+	collectionConfig.Tuning = nil
+	if err := collection.setConfig(collectionConfig); err != nil {
+		return nil, err
+	}
+	log.Info().Msgf("set search opt of collection [%s] to %+v", collectionName, opt)
+	return collection, nil
+}
+
+// TuneCollection evaluates nprobe ratios and reorders against the exact neighbors of sampled vectors
+// and stores the option with the least mean latency which reaches the target recall. A HNSW collection
+// is tuned by raising ef_search until the target recall is reached.
+//
+// The nprobe ratios are the powers of 2 up to the largest nlist of the segments, as a fraction of it,
+// so every segment probes the same share of its own nlist. For each reorder, nprobe is raised until
+// the target recall is reached, a larger nprobe is only slower.
+func TuneCollection(ctx context.Context, collectionName string, req *TuneRequest) (*TuneResponse, error) {
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if req.TargetRecall == 0 {
+		req.TargetRecall = defaultTuneTargetRecall
+	}
+	if req.TargetRecall < 0 || req.TargetRecall > 1 {
+		return nil, vqerrors.InvalidArgument("target_recall %v must be in (0, 1]", req.TargetRecall)
+	}
+	if req.SampleSize == 0 {
+		req.SampleSize = defaultEvalSampleSize
+	}
+	topkOpt := QueryOpt{TopK: req.TopK}
+	collection.CheckSearchOpt(&topkOpt)
+	req.TopK = topkOpt.TopK
+
+	indexType := collection.Config().IndexType
+	var maxNlist int32
+	for _, seg := range collection.GetSearchableSegments() {
+		if stat, err := seg.Statistics(); err == nil && !stat.IndexStatistics.IsBrute && stat.IndexStatistics.Nlist > maxNlist {
+			maxNlist = stat.IndexStatistics.Nlist
+		}
+	}
+	if maxNlist == 0 && indexType != hnsw.IndexType {
+		return nil, vqerrors.NotReady("collection [%s] has no trained segment to tune", collectionName)
+	}
+
+	eval, err := newEvaluation(ctx, collection, nil, req.SampleSize, req.TopK)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &TuneResponse{CollectionName: collectionName, Results: make([]EvalResult, 0)}
+	reorders := tuneReorders(req.TopK)
+	if indexType == scann.IndexTypeFAISS {
+		reorders = []int{req.TopK}
+	}
+	if indexType == hnsw.IndexType {
+		// the graph has no partitions to probe and nothing to reorder, only ef_search is tuned
+		reorders = nil
+	}
+	var best *EvalResult
+	for _, efSearch := range tuneEfSearches(req.TopK, indexType) {
+		opt := QueryOpt{TopK: req.TopK, EfSearch: efSearch}
+		collection.CheckSearchOpt(&opt)
+		result, err := eval.run(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		resp.Results = append(resp.Results, *result)
+		// a larger ef_search is only slower
+		if result.Recall >= req.TargetRecall {
+			best = result
+			break
+		}
+	}
+	for _, reorder := range reorders {
+		for nprobe := int32(1); ; nprobe *= 2 {
+			if nprobe > maxNlist {
+				nprobe = maxNlist
+			}
+			opt := QueryOpt{TopK: req.TopK, NProbeRatio: float64(nprobe) / float64(maxNlist), Reorder: reorder}
+			collection.CheckSearchOpt(&opt)
+			result, err := eval.run(ctx, opt)
+			if err != nil {
+				return nil, err
+			}
+			resp.Results = append(resp.Results, *result)
+			if result.Recall >= req.TargetRecall {
+				if best == nil || result.LatencyMeanMs < best.LatencyMeanMs {
+					best = result
+				}
+				break
+			}
+			if nprobe == maxNlist {
+				break
+			}
+		}
+	}
+	if best == nil {
+		log.Warn().Msgf("tune collection [%s] did not reach recall %v", collectionName, req.TargetRecall)
+		resp.SearchOpt = collection.Config().SearchOpt
+		return resp, nil
+	}
+
+	collectionConfig := collection.Config()
+	if indexType == hnsw.IndexType {
+		collectionConfig.SearchOpt.EfSearch = best.EfSearch
+	} else {
+		collectionConfig.SearchOpt.NProbe = 0
+		collectionConfig.SearchOpt.NProbeRatio = best.NProbeRatio
+		collectionConfig.SearchOpt.Reorder = best.Reorder
+	}
+	collectionConfig.Tuning = &SearchTuning{
+		TargetRecall:  req.TargetRecall,
+		TopK:          req.TopK,
+		Queries:       len(eval.queries),
+		Recall:        best.Recall,
+		LatencyMeanMs: best.LatencyMeanMs,
+		LatencyP99Ms:  best.LatencyP99Ms,
+		TunedAt:       time.Now().Unix(),
+	}
+	if err := collection.setConfig(collectionConfig); err != nil {
+		return nil, err
+	}
+	resp.Applied = true
+	resp.SearchOpt = collectionConfig.SearchOpt
+	resp.Tuning = collectionConfig.Tuning
+	log.Info().Msgf("tune collection [%s] success, nprobe_ratio %v, reorder %d, ef_search %d, recall %.4f, latency %.2fms",
+		collectionName, best.NProbeRatio, best.Reorder, best.EfSearch, best.Recall, best.LatencyMeanMs)
+	return resp, nil
+}
+
+// tuneEfSearches returns multiples of topk up to the ef_search limit for a HNSW collection, none for others.
+func tuneEfSearches(topk int, indexType string) []int {
+	if indexType != hnsw.IndexType {
+		return nil
+	}
+	maxEfSearch := config.GlobalConfig.LimitConfig.MaxEfSearch
+	efSearches := make([]int, 0)
+	for _, factor := range []int{1, 2, 4, 8, 16} {
+		if efSearch := topk * factor; efSearch <= maxEfSearch {
+			efSearches = append(efSearches, efSearch)
+		}
+	}
+	if len(efSearches) == 0 {
+		efSearches = append(efSearches, maxEfSearch)
+	}
+	return efSearches
+}
+
+// tuneReorders returns multiples of topk up to the reorder limit, reordering less than topk
+// candidates can not fill the results.
+func tuneReorders(topk int) []int {
+	maxReorder := config.GlobalConfig.LimitConfig.MaxReorder
+	reorders := make([]int, 0)
+	for _, factor := range []int{1, 2, 4, 8, 16} {
+		if reorder := topk * factor; reorder <= maxReorder {
+			reorders = append(reorders, reorder)
+		}
+	}
+	if len(reorders) == 0 {
+		reorders = append(reorders, maxReorder)
+	}
+	return reorders
+}
@@ -2,17 +2,21 @@ package core
 
 import "C"
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"go.uber.org/atomic"
 	"golang.org/x/sys/unix"
+	"math"
 	"os"
 	"os/exec"
 	"strconv"
+	"sync"
 	"syscall"
 	scann "vqlite/engine/go-scann"
+	"vqlite/engine/hnsw"
+	vqerrors "vqlite/errors"
 	"vqlite/utils"
 )
 
@@ -21,11 +25,17 @@ type SegmentConfig struct {
 	SegmentId      uint64
 	SegmentWorkDir string
 	Dim            int
+	// IndexParams the index is created with and TrainParams of the last training,
+	// so a training can be reproduced
+	IndexParams scann.IndexParams
+	TrainParams scann.TrainParams
+	// VectorStats measures the drift of vectors added since the last full training, guarded by vectorStatsLock
+	VectorStats VectorStats
 }
 
 // SegmentIndex segment index
 type SegmentIndex struct {
-	VIndexC     *scann.ScaNNIndex
+	VIndexC     VectorIndex
 	Sealed      bool
 	hasNewIndex atomic.Bool
 	isTraining  atomic.Bool
@@ -35,6 +45,14 @@ type Segment struct {
 	SegmentConfig   SegmentConfig
 	SegmentIndex    SegmentIndex
 	SegmentMetadata SegmentMetadata
+	filesShared     atomic.Bool // files may be hard linked by a snapshot
+	dirty           atomic.Bool // metadata or config changed since the last dump
+	vectorStatsLock sync.Mutex
+	// deletes unshare the files holding only the read lock of the collection, the others wait for the index to load
+	unshareLock sync.Mutex
+	// an in-memory index is trained in place holding the read lock of the engine, so adds are turned away
+	// while it trains, waiting for the write lock there would block every search after them
+	inPlaceTrainLock sync.RWMutex
 }
 
 const (
@@ -66,7 +84,7 @@ func TrainSegmentByCmd(segmentWorkDir string, numThreads int) int {
 		return SegmentConfigFileNotExists
 	}
 
-	seg, err := NewSegment(0, segmentWorkDir, 0)
+	seg, err := NewSegment(0, segmentWorkDir, 0, scann.IndexParams{})
 
 	if err != nil {
 		log.Error().Err(err).Msg("new segment error")
@@ -82,7 +100,9 @@ func TrainSegmentByCmd(segmentWorkDir string, numThreads int) int {
 
 	seg.LoadIndex()
 
-	err = seg.SegmentIndex.VIndexC.Train(numThreads) // train index and dump index
+	trainParams := seg.SegmentConfig.TrainParams
+	trainParams.Threads = numThreads
+	err = seg.SegmentIndex.VIndexC.Train(trainParams) // train index and dump index
 
 	if err != nil {
 		log.Error().Err(err).Msg("train segment index error")
@@ -99,11 +119,11 @@ func TrainSegmentByCmd(segmentWorkDir string, numThreads int) int {
 
 }
 
-func NewSegment(segmentId uint64, segmentWorkDir string, dim int) (*Segment, error) {
-	var vIndex *scann.ScaNNIndex
+func NewSegment(segmentId uint64, segmentWorkDir string, dim int, indexParams scann.IndexParams) (*Segment, error) {
+	var vIndex VectorIndex
 	var err error
 	if dim > 0 {
-		vIndex, err = scann.NewScaNNIndex(segmentWorkDir, dim, segmentId)
+		vIndex, err = newVectorIndex(segmentWorkDir, dim, segmentId, indexParams)
 		if err != nil {
 			log.Error().Err(err).Msg("create index error")
 			return nil, err
@@ -114,6 +134,7 @@ func NewSegment(segmentId uint64, segmentWorkDir string, dim int) (*Segment, err
 			SegmentId:      segmentId,
 			SegmentWorkDir: segmentWorkDir,
 			Dim:            dim,
+			IndexParams:    indexParams,
 		},
 		SegmentIndex: SegmentIndex{
 			VIndexC:     vIndex,
@@ -126,11 +147,31 @@ func NewSegment(segmentId uint64, segmentWorkDir string, dim int) (*Segment, err
 	return segment, nil
 }
 
-func (s *Segment) Search(queryVecs []float32, opt QueryOpt) ([][]scann.VidScore, error) {
-	return s.SegmentIndex.VIndexC.Search(queryVecs, opt.TopK, opt.NProbe, opt.Reorder)
+// NProbe returns the nprobe of the search of the segment, opt.NProbeRatio of its nlist when opt.NProbe is 0.
+func (s *Segment) NProbe(opt QueryOpt) int {
+	if opt.NProbe > 0 || opt.NProbeRatio <= 0 || s.SegmentIndex.VIndexC == nil {
+		return opt.NProbe
+	}
+	nlist := s.SegmentIndex.VIndexC.Statistics().Nlist
+	nprobe := int(math.Ceil(opt.NProbeRatio * float64(nlist)))
+	if nprobe < 1 {
+		nprobe = 1
+	}
+	return nprobe
 }
 
-func (s *Segment) BatchAddDocuments(documents *BatchAddDocumentsRequest) {
+func (s *Segment) Search(ctx context.Context, queryVecs []float32, opt QueryOpt) ([][]scann.VidScore, error) {
+	return s.SegmentIndex.VIndexC.Search(ctx, queryVecs, opt)
+}
+
+func (s *Segment) BatchAddDocuments(documents *BatchAddDocumentsRequest) error {
+	release, err := s.admitAdd()
+	if err != nil {
+		return err
+	}
+	defer release()
+	s.UnshareFiles()
+	s.SetDirty()
 
 	vectorsIds := make([]int64, 0)
 	vectors := make([][]float32, 0)
@@ -165,11 +206,21 @@ func (s *Segment) BatchAddDocuments(documents *BatchAddDocumentsRequest) {
 			}
 		}
 	}
-	s.SegmentIndex.VIndexC.AddWithIDs(vectors, vectorsIds)
-
+	if err := s.SegmentIndex.VIndexC.AddWithIDs(vectors, vectorsIds); err != nil {
+		return err
+	}
+	s.addVectorStats(vectors)
+	return nil
 }
 
-func (s *Segment) AddDocument(document *AddDocumentRequest) {
+func (s *Segment) AddDocument(document *AddDocumentRequest) error {
+	release, err := s.admitAdd()
+	if err != nil {
+		return err
+	}
+	defer release()
+	s.UnshareFiles()
+	s.SetDirty()
 
 	// global increment doc id
 	documentId := int64(s.SegmentMetadata.Size())
@@ -204,15 +255,36 @@ func (s *Segment) AddDocument(document *AddDocumentRequest) {
 		}
 	}
 
-	s.SegmentIndex.VIndexC.AddWithIDs(document.Vectors, vectorsIds)
+	if err := s.SegmentIndex.VIndexC.AddWithIDs(document.Vectors, vectorsIds); err != nil {
+		return err
+	}
+	s.addVectorStats(document.Vectors)
+	return nil
 }
 
 func (s *Segment) DeleteDocument(vqid string) bool {
-	return s.SegmentMetadata.DeleteByVqid(vqid)
+	documentId, deleted := s.SegmentMetadata.DeleteByVqid(vqid)
+	if deleted {
+		// tombstone the vectors of the document, so searches of a HNSW index still find topk others.
+		// The index is unshared first, unsharing loads it again from the dumped files without the tombstones
+		s.UnshareFiles()
+		if s.SegmentIndex.VIndexC != nil {
+			s.SegmentIndex.VIndexC.Delete(func(vid int64) bool {
+				vidDocumentId, _ := utils.DecodeVectorId(vid)
+				return vidDocumentId == int64(documentId)
+			})
+		}
+		s.SetDirty()
+	}
+	return deleted
 }
 
 func (s *Segment) UpdateDocumentMetadata(document *UpdateDocumentMetadataRequest) int {
-	return s.SegmentMetadata.Update(document.Vqid, document.Metadata)
+	updatedCount := s.SegmentMetadata.Update(document.Vqid, document.Metadata)
+	if updatedCount > 0 {
+		s.SetDirty()
+	}
+	return updatedCount
 }
 
 func (s *Segment) GetDocumentMetadata(vqid string, checkDuplicate bool) []*Metadata {
@@ -255,25 +327,73 @@ func (s *Segment) IsSearchable() bool {
 
 func (s *Segment) Statistics() (*SegmentStatistics, error) {
 	if s.SegmentIndex.VIndexC == nil {
-		return nil, errors.New("index is nil")
+		return nil, vqerrors.NotReady("index is nil")
 	}
 	indexStatistics := s.SegmentIndex.VIndexC.Statistics()
 	vectorCount := indexStatistics.VidSize
+	var trainDelta, trainDrift float64
+	if indexStatistics.IndexSize > 0 && !indexStatistics.IsBrute {
+		if trainDelta, trainDrift = s.driftStats(indexStatistics); math.IsInf(trainDelta, 1) {
+			trainDelta = 0
+		}
+	}
 	return &SegmentStatistics{
 		SegmentId:       s.SegmentConfig.SegmentId,
 		Sealed:          s.SegmentIndex.Sealed,
 		Dim:             s.SegmentConfig.Dim,
+		Dirty:           s.IsDirty(),
 		IndexStatistics: indexStatistics,
+		TrainOpt:        segmentTrainOpt(s.SegmentConfig),
+		TrainDelta:      trainDelta,
+		TrainDrift:      trainDrift,
 		VectorCount:     vectorCount,
 		DocCount:        int64(s.SegmentMetadata.Size()),
 	}, nil
 }
 
-func (s *Segment) Train(numThreads int) error {
+// Train trains the index of the segment in a child process with the params recorded in the
+// segment config, the new index is created with indexParams. An in-memory index is trained in place.
+func (s *Segment) Train(numThreads int, indexParams scann.IndexParams, trainParams scann.TrainParams) error {
 	if !s.SegmentIndex.isTraining.CompareAndSwap(false, true) {
-		return errors.New("segment is training")
+		return vqerrors.NotReady("segment is training")
 	}
+	defer s.SegmentIndex.isTraining.Store(false)
+
+	trainParams.Threads = numThreads
+	// the storage and index type are fixed when the segment is created
+	indexParams.Storage = s.SegmentConfig.IndexParams.Storage
+	indexParams.IndexType = s.SegmentConfig.IndexParams.IndexType
+	// the vectors added so far are the ones trained on, later ones stay in the delta
+	trainedStats := s.vectorStats()
+	if s.InMemory() {
+		// there are no files for a child process to train, so the index keeps the params it was created with
+		s.SegmentConfig.TrainParams = trainParams
+		s.inPlaceTrainLock.Lock()
+		err := s.SegmentIndex.VIndexC.Train(trainParams)
+		s.inPlaceTrainLock.Unlock()
+		if err != nil {
+			return err
+		}
+	} else {
+		s.SegmentConfig.IndexParams = indexParams
+		s.SegmentConfig.TrainParams = trainParams
+		if err := s.trainByCmd(numThreads); err != nil {
+			return err
+		}
+	}
+	if trainParams.Type != scann.TrainTypeAdd {
+		s.vectorStatsLock.Lock()
+		s.SegmentConfig.VectorStats.fold(trainedStats)
+		s.vectorStatsLock.Unlock()
+		s.SetDirty()
+	}
+	s.SetHasNewIndex()
+	s.LoadIndex()
+	return nil
+}
 
+// trainByCmd dumps the segment and trains its index in a child process, which dumps the new index.
+func (s *Segment) trainByCmd(numThreads int) error {
 	s.DumpConfig()   // dump segment config
 	s.DumpMetadata() // dump segment metadata and raw data
 
@@ -287,20 +407,23 @@ func (s *Segment) Train(numThreads int) error {
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	// register before start, so shutdown either waits for the process or refuses to start it
+	if err := registerTrainProcess(cmd); err != nil {
+		return err
+	}
+	defer unregisterTrainProcess(cmd)
 	if err := cmd.Start(); err != nil {
 		log.Error().Err(err).Msg("failed to start command")
+		return err
 	}
 	if err := cmd.Wait(); err != nil {
 		log.Error().Err(err).Msg("failed to wait command")
 
 		exitError, ok := err.(*exec.ExitError)
 		if !ok || exitError.ExitCode() != TrainSuccess {
-			return err
+			return vqerrors.Wrap(vqerrors.CodeInternal, err, "train segment %d failed", s.SegmentConfig.SegmentId)
 		}
 	}
-	s.SegmentIndex.isTraining.Store(false)
-	s.SetHasNewIndex()
-	s.LoadIndex()
 	return nil
 }
 
@@ -312,12 +435,13 @@ func (s *Segment) DropIndex() error {
 
 func (s *Segment) Drop() error {
 	// delete all metadata
-	err := utils.DeleteDir(s.SegmentConfig.SegmentWorkDir)
-	if err != nil {
-		return err
+	if !s.InMemory() {
+		if err := utils.DeleteDir(s.SegmentConfig.SegmentWorkDir); err != nil {
+			return err
+		}
 	}
 	// delete index
-	err = s.DropIndex()
+	err := s.DropIndex()
 	if err != nil {
 		return err
 	}
@@ -325,6 +449,10 @@ func (s *Segment) Drop() error {
 }
 
 func (s *Segment) DumpConfig() error {
+	if s.InMemory() {
+		return nil
+	}
+	s.UnshareFiles()
 	log.Info().Msgf("dump segment config, segmentId:%v", s.SegmentConfig.SegmentId)
 
 	// create segment dir
@@ -333,7 +461,10 @@ func (s *Segment) DumpConfig() error {
 	}
 
 	segmentConfigSerializeFilename := utils.Join(s.SegmentConfig.SegmentWorkDir, "config.gob")
+	// vector stats may be changed by adds while dumping
+	s.vectorStatsLock.Lock()
 	err := utils.Dump(s.SegmentConfig, segmentConfigSerializeFilename)
+	s.vectorStatsLock.Unlock()
 	if err != nil {
 		log.Error().Err(err).Msg("dump segment config error")
 	}
@@ -342,6 +473,10 @@ func (s *Segment) DumpConfig() error {
 }
 
 func (s *Segment) DumpMetadata() error {
+	if s.InMemory() {
+		return nil
+	}
+	s.UnshareFiles()
 	log.Info().Msgf("dump segment metadata, segmentId:%v", s.SegmentConfig.SegmentId)
 
 	// create segment dir
@@ -349,7 +484,10 @@ func (s *Segment) DumpMetadata() error {
 		utils.CreateDirPath(s.SegmentConfig.SegmentWorkDir)
 	}
 	segmentMetadataSerializeFilename := utils.Join(s.SegmentConfig.SegmentWorkDir, "metadata.gob")
+	// metadata may be deleted or updated while dumping
+	s.SegmentMetadata.metadataRwLock.RLock()
 	err := utils.Dump(s.SegmentMetadata.metadata, segmentMetadataSerializeFilename)
+	s.SegmentMetadata.metadataRwLock.RUnlock()
 	if err != nil {
 		log.Error().Err(err).Msg("dump segment metadata error")
 	}
@@ -357,6 +495,10 @@ func (s *Segment) DumpMetadata() error {
 }
 
 func (s *Segment) DumpIndex() error {
+	if s.InMemory() {
+		return nil
+	}
+	s.UnshareFiles()
 	log.Info().Msgf("dump segment index, segmentId:%v", s.SegmentConfig.SegmentId)
 
 	// create segment dir
@@ -367,14 +509,24 @@ func (s *Segment) DumpIndex() error {
 	return err
 }
 
-func (s *Segment) Dump() {
+func (s *Segment) Dump() error {
 	var err error
+	// an in-memory segment stays dirty, it is never dumped
+	if s.InMemory() {
+		return nil
+	}
+
+	// clear before dumping, so changes made while dumping mark the segment dirty again
+	wasDirty := s.dirty.Swap(false)
 
 	// dump segment metadata
 	err = s.DumpMetadata()
 	if err != nil {
 		log.Error().Err(err).Msg("dump segment metadata error")
-		return
+		if wasDirty {
+			s.SetDirty()
+		}
+		return err
 	}
 	log.Info().Msg("dump segment metadata success")
 
@@ -382,10 +534,24 @@ func (s *Segment) Dump() {
 	err = s.DumpConfig()
 	if err != nil {
 		log.Error().Err(err).Msg("dump segment config error")
-		return
+		if wasDirty {
+			s.SetDirty()
+		}
+		return err
 	}
 	log.Info().Msg("dump segment config success")
 
+	// a HNSW index keeps the added vectors in memory, while the engine writes them on add
+	if s.SegmentConfig.IndexParams.IndexType == hnsw.IndexType {
+		if err = s.DumpIndex(); err != nil {
+			log.Error().Err(err).Msg("dump segment index error")
+			if wasDirty {
+				s.SetDirty()
+			}
+			return err
+		}
+	}
+
 	// dump segment index
 	//err = s.DumpIndex()
 	//if err != nil {
@@ -393,6 +559,7 @@ func (s *Segment) Dump() {
 	//	return
 	//}
 	//log.Info().Msg("dump segment index success")
+	return nil
 }
 
 func (s *Segment) LoadConfig() {
@@ -404,10 +571,13 @@ func (s *Segment) LoadConfig() {
 		log.Error().Msgf("segment config file not exist:%v", segmentConfigSerializeFilename)
 		return
 	}
+	segmentWorkDirTemp := s.SegmentConfig.SegmentWorkDir
 	err := utils.Load(&s.SegmentConfig, segmentConfigSerializeFilename)
 	if err != nil {
 		log.Error().Err(err).Msg("load segment config error")
 	}
+	// the stored SegmentWorkDir is stale when the collection dir was moved or restored, so keep the real one
+	s.SegmentConfig.SegmentWorkDir = segmentWorkDirTemp
 }
 
 func (s *Segment) LoadMetadata() {
@@ -432,7 +602,7 @@ func (s *Segment) LoadIndex() {
 	if s.SegmentIndex.VIndexC == nil {
 		log.Info().Msgf("load segment index, new index ,segmentId:%v", s.SegmentConfig.SegmentId)
 
-		newIndex, err := scann.NewScaNNIndex(s.SegmentConfig.SegmentWorkDir, s.SegmentConfig.Dim, s.SegmentConfig.SegmentId)
+		newIndex, err := newVectorIndex(s.SegmentConfig.SegmentWorkDir, s.SegmentConfig.Dim, s.SegmentConfig.SegmentId, s.SegmentConfig.IndexParams)
 		if err != nil {
 			log.Error().Err(err).Msg("create index error")
 		}
@@ -442,7 +612,7 @@ func (s *Segment) LoadIndex() {
 		log.Info().Msgf("load segment index, replace index ,segmentId:%v", s.SegmentConfig.SegmentId)
 
 		// load new index
-		newIndex, err := scann.NewScaNNIndex(s.SegmentConfig.SegmentWorkDir, s.SegmentConfig.Dim, s.SegmentConfig.SegmentId)
+		newIndex, err := newVectorIndex(s.SegmentConfig.SegmentWorkDir, s.SegmentConfig.Dim, s.SegmentConfig.SegmentId, s.SegmentConfig.IndexParams)
 		if err != nil {
 			log.Error().Err(err).Msg("create index error")
 		}
@@ -458,9 +628,68 @@ func (s *Segment) Load() {
 	s.LoadConfig()
 	s.LoadIndex()
 	s.LoadMetadata()
+	// files on disk may be hard linked by a snapshot
+	s.SetFilesShared()
+}
+
+// admitAdd lets an add into the segment unless its index is trained in place, the add fails
+// with not ready then and may be retried. The returned func must be called once the add is done.
+func (s *Segment) admitAdd() (func(), error) {
+	if !s.inPlaceTrainLock.TryRLock() {
+		return nil, vqerrors.NotReady("segment %d is training", s.SegmentConfig.SegmentId)
+	}
+	return s.inPlaceTrainLock.RUnlock, nil
+}
+
+// SetFilesShared marks the segment files as possibly hard linked elsewhere,
+// they will be unshared before the next write.
+func (s *Segment) SetFilesShared() {
+	s.filesShared.Store(true)
+}
+
+// UnshareFiles gives the segment its own copy of every hard linked file before writing to it.
+func (s *Segment) UnshareFiles() {
+	s.unshareLock.Lock()
+	defer s.unshareLock.Unlock()
+	if !s.filesShared.Load() {
+		return
+	}
+	s.filesShared.Store(false)
+	unshared, err := utils.BreakHardLinks(s.SegmentConfig.SegmentWorkDir)
+	if err != nil {
+		log.Error().Err(err).Msgf("unshare segment files error, segmentId:%v", s.SegmentConfig.SegmentId)
+	}
+	// the engine keeps the files it loaded open and would still write to the linked ones, so the
+	// index is loaded again from the copies, nothing was written to it since it was dumped or loaded
+	if unshared > 0 && s.SegmentIndex.VIndexC != nil {
+		s.SetHasNewIndex()
+		s.LoadIndex()
+	}
 }
 
+// SetDirty marks the segment metadata or config as changed since the last dump.
+func (s *Segment) SetDirty() {
+	s.dirty.Store(true)
+}
+
+func (s *Segment) IsDirty() bool {
+	return s.dirty.Load()
+}
+
+// InMemory reports whether the index of the segment is kept in memory only, nothing of it is dumped.
+func (s *Segment) InMemory() bool {
+	return s.SegmentConfig.IndexParams.Storage == scann.StorageMemory
+}
+
+func (s *Segment) IsTraining() bool {
+	return s.SegmentIndex.isTraining.Load()
+}
+
+// SetHasNewIndex marks a new index dumped by a training to be loaded, an in-memory index is trained in place.
 func (s *Segment) SetHasNewIndex() {
+	if s.InMemory() {
+		return
+	}
 	s.SegmentIndex.hasNewIndex.Store(true)
 }
 func (s *Segment) SetNoNewIndex() {
@@ -32,7 +32,8 @@ func (sm *SegmentMetadata) GetByid(id int) *Metadata {
 	return sm.metadata[id]
 }
 
-func (sm *SegmentMetadata) DeleteByVqid(vqid string) bool {
+// DeleteByVqid deletes the first document of vqid and returns its id.
+func (sm *SegmentMetadata) DeleteByVqid(vqid string) (int, bool) {
 	sm.metadataRwLock.Lock()
 	defer sm.metadataRwLock.Unlock()
 
@@ -42,10 +43,10 @@ func (sm *SegmentMetadata) DeleteByVqid(vqid string) bool {
 		}
 		if sm.metadata[i].Vqid == vqid {
 			sm.metadata[i] = nil
-			return true
+			return i, true
 		}
 	}
-	return false
+	return -1, false
 
 }
 
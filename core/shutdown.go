@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+	vqerrors "vqlite/errors"
+)
+
+// trainProcesses tracks the running train subprocesses, so shutdown can wait for or cancel them.
+var trainProcesses = struct {
+	lock         sync.Mutex
+	cmds         map[*exec.Cmd]struct{}
+	wg           sync.WaitGroup
+	shuttingDown bool
+}{
+	cmds: make(map[*exec.Cmd]struct{}),
+}
+
+func registerTrainProcess(cmd *exec.Cmd) error {
+	trainProcesses.lock.Lock()
+	defer trainProcesses.lock.Unlock()
+	if trainProcesses.shuttingDown {
+		return vqerrors.NotReady("vqlite is shutting down, train is not allowed")
+	}
+	trainProcesses.cmds[cmd] = struct{}{}
+	trainProcesses.wg.Add(1)
+	return nil
+}
+
+func unregisterTrainProcess(cmd *exec.Cmd) {
+	trainProcesses.lock.Lock()
+	defer trainProcesses.lock.Unlock()
+	if _, ok := trainProcesses.cmds[cmd]; !ok {
+		return
+	}
+	delete(trainProcesses.cmds, cmd)
+	trainProcesses.wg.Done()
+}
+
+// waitTrainProcesses waits for all train subprocesses until ctx is done,
+// then the remaining subprocesses are terminated.
+func waitTrainProcesses(ctx context.Context) error {
+	trainProcesses.lock.Lock()
+	trainProcesses.shuttingDown = true
+	running := len(trainProcesses.cmds)
+	trainProcesses.lock.Unlock()
+	if running == 0 {
+		return nil
+	}
+	log.Info().Msgf("wait for %d train processes", running)
+
+	done := make(chan struct{})
+	go func() {
+		trainProcesses.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	trainProcesses.lock.Lock()
+	for cmd := range trainProcesses.cmds {
+		if cmd.Process == nil {
+			continue
+		}
+		log.Warn().Msgf("terminate train process %d", cmd.Process.Pid)
+		_ = cmd.Process.Signal(syscall.SIGTERM)
+	}
+	trainProcesses.lock.Unlock()
+
+	// give the processes a moment to exit, they are killed with us by Pdeathsig anyway
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+	}
+	return vqerrors.Wrap(vqerrors.CodeInternal, ctx.Err(), "train processes canceled")
+}
+
+// Shutdown stops VQLite after the HTTP server stopped accepting requests.
+//
+// It waits for running train subprocesses until ctx is done and cancels the rest, dumps
+// the metadata and config of every loaded collection and releases all indexes.
+// An error is returned if a train was canceled or a collection failed to dump.
+func Shutdown(ctx context.Context) error {
+	var firstErr error
+	if err := waitTrainProcesses(ctx); err != nil {
+		log.Error().Err(err).Msg("shutdown train processes error")
+		firstErr = err
+	}
+
+	for _, col := range VqliteCollectionList.List() {
+		if err := col.Close(); err != nil {
+			log.Error().Err(err).Msgf("shutdown collection [%s] error", col.Name())
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Close dumps the collection and releases its indexes for shutdown.
+func (c *Collection) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.IsLoaded() {
+		return nil
+	}
+	err := c.Dump()
+	releaseSegments(c.Segments)
+	c.Segments = make([]*Segment, 0)
+	_ = c.setState(CollectionStateUnloaded, nil)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("close collection [%s] success", c.Name())
+	return nil
+}
@@ -0,0 +1,338 @@
+package core
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"vqlite/config"
+	vqerrors "vqlite/errors"
+	"vqlite/utils"
+)
+
+const (
+	SnapshotVersion      = 1
+	SnapshotManifestName = "MANIFEST.json"
+	// SnapshotSha256Header is the hex sha256 of a snapshot archive sent over HTTP
+	SnapshotSha256Header = "X-Snapshot-Sha256"
+)
+
+// SnapshotFile describes one file stored in a snapshot archive.
+type SnapshotFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256,omitempty"`
+}
+
+// SnapshotManifest is written as the last entry of a snapshot archive.
+type SnapshotManifest struct {
+	Version    int            `json:"version"`
+	Name       string         `json:"name,omitempty"`
+	Collection string         `json:"collection"`
+	Dim        int            `json:"dim"`
+	IndexType  string         `json:"index_type"`
+	Segments   []uint64       `json:"segments"`
+	CreatedAt  int64          `json:"created_at"`
+	Files      []SnapshotFile `json:"files"`
+}
+
+// SnapshotArchive is a snapshot archive kept in a temp file until it is sent, Close removes the file.
+type SnapshotArchive struct {
+	*os.File
+	Manifest *SnapshotManifest
+	Size     int64
+	Sha256   string
+}
+
+func (a *SnapshotArchive) Close() error {
+	err := a.File.Close()
+	if removeErr := os.Remove(a.File.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// SnapshotCollection dumps the collection and writes it as a tar archive to a temp file in the data path.
+//
+// Writes on the collection are blocked until the archive is completely written, so the archive is a
+// consistent view of all segments, but not while the caller sends it, which may take much longer.
+func SnapshotCollection(collectionName string) (*SnapshotArchive, error) {
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if err := collection.checkOnDisk("snapshot"); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(config.GlobalConfig.ServiceConfig.DataPath, ".snapshot_*.tar")
+	if err != nil {
+		return nil, err
+	}
+	archive := &SnapshotArchive{File: f}
+	hash := sha256.New()
+	archive.Manifest, err = collection.Snapshot(io.MultiWriter(f, hash))
+	if err == nil {
+		archive.Size, err = f.Seek(0, io.SeekCurrent)
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = archive.Close()
+		return nil, err
+	}
+	archive.Sha256 = hex.EncodeToString(hash.Sum(nil))
+	return archive, nil
+}
+
+// RestoreCollection restores a snapshot archive read from r as a new collection named collectionName.
+func RestoreCollection(collectionName string, r io.Reader) (*SnapshotManifest, error) {
+	if err := checkNewCollectionName(collectionName); err != nil {
+		return nil, err
+	}
+	dataPath := config.GlobalConfig.ServiceConfig.DataPath
+	collectionPath := utils.Join(dataPath, collectionName)
+
+	// extract to a hidden temp dir first, so a broken archive never shows up as a collection
+	tempPath := utils.Join(dataPath, fmt.Sprintf(".restore_%s_%d", collectionName, time.Now().UnixNano()))
+	manifest, err := extractSnapshot(r, tempPath)
+	if err == nil {
+		err = applySnapshotIndexType(manifest, tempPath)
+	}
+	if err != nil {
+		_ = utils.DeleteDir(tempPath)
+		return nil, err
+	}
+	if err := os.Rename(tempPath, collectionPath); err != nil {
+		_ = utils.DeleteDir(tempPath)
+		return nil, err
+	}
+
+	col, err := NewCollection(collectionName, manifest.Dim)
+	if err != nil {
+		_ = utils.DeleteDir(collectionPath)
+		return nil, err
+	}
+	if err := col.Load(); err != nil {
+		discardRestored(col)
+		return nil, err
+	}
+	log.Info().Msgf("restore collection [%s] from snapshot of [%s] success", collectionName, manifest.Collection)
+	return manifest, nil
+}
+
+// Snapshot dumps all segments of the collection and writes them with a manifest to w as a tar archive.
+//
+// The collection lock is held for the whole operation, so documents can not be added, updated
+// or deleted while the snapshot is written.
+//
+// Parameters:
+// - w: The writer the tar archive is written to.
+//
+// Returns:
+// - *SnapshotManifest: The manifest written to the archive.
+// - error: An error if dumping or writing the archive failed.
+func (c *Collection) Snapshot(w io.Writer) (*SnapshotManifest, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.IsLoaded() {
+		return nil, vqerrors.NotReady("collection [%s] is not loaded", c.Name())
+	}
+	if err := c.dumpAll(); err != nil {
+		return nil, err
+	}
+
+	manifest := &SnapshotManifest{
+		Version:    SnapshotVersion,
+		Collection: c.Name(),
+		Dim:        c.Dim,
+		IndexType:  c.IndexType,
+		Segments:   make([]uint64, 0, len(c.Segments)),
+		CreatedAt:  time.Now().Unix(),
+		Files:      make([]SnapshotFile, 0),
+	}
+
+	tw := tar.NewWriter(w)
+	for _, seg := range c.Segments {
+		manifest.Segments = append(manifest.Segments, seg.SegmentConfig.SegmentId)
+		segmentDir := filepath.Base(seg.SegmentConfig.SegmentWorkDir)
+		err := filepath.Walk(seg.SegmentConfig.SegmentWorkDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			relPath, err := filepath.Rel(seg.SegmentConfig.SegmentWorkDir, path)
+			if err != nil {
+				return err
+			}
+			file, err := writeSnapshotFile(tw, path, filepath.ToSlash(filepath.Join(segmentDir, relPath)), info)
+			if err != nil {
+				return err
+			}
+			manifest.Files = append(manifest.Files, *file)
+			return nil
+		})
+		if err != nil {
+			log.Error().Err(err).Msgf("snapshot collection [%s] error", c.Name())
+			return nil, err
+		}
+	}
+	// a collection which was never configured has no config file
+	if info, err := os.Stat(c.configFilePath()); err == nil {
+		file, err := writeSnapshotFile(tw, c.configFilePath(), CollectionConfigFileName, info)
+		if err != nil {
+			log.Error().Err(err).Msgf("snapshot collection [%s] error", c.Name())
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, *file)
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    SnapshotManifestName,
+		Mode:    0600,
+		Size:    int64(len(manifestData)),
+		ModTime: time.Unix(manifest.CreatedAt, 0),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(manifestData); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	log.Info().Msgf("snapshot collection [%s] success, segments: %d, files: %d", c.Name(), len(manifest.Segments), len(manifest.Files))
+	return manifest, nil
+}
+
+// applySnapshotIndexType checks the index type of the manifest and records it in the collection config
+// extracted to dir, which an archive of a collection never configured does not have.
+// Archives without an index type keep the one of their config or segments.
+func applySnapshotIndexType(manifest *SnapshotManifest, dir string) error {
+	if manifest.IndexType == "" {
+		return nil
+	}
+	if !validIndexType(manifest.IndexType) {
+		return vqerrors.InvalidArgument("unknown index_type [%s] in snapshot manifest", manifest.IndexType)
+	}
+	collectionConfig := CollectionConfig{}
+	filename := utils.Join(dir, CollectionConfigFileName)
+	if utils.Exists(filename) {
+		if err := utils.Load(&collectionConfig, filename); err != nil {
+			return vqerrors.Wrap(vqerrors.CodeInvalidArgument, err, "invalid collection config in snapshot")
+		}
+		if collectionConfig.IndexType != "" && collectionConfig.IndexType != manifest.IndexType {
+			return vqerrors.InvalidArgument("snapshot index_type [%s] does not match the collection config [%s]",
+				manifest.IndexType, collectionConfig.IndexType)
+		}
+	}
+	collectionConfig.IndexType = manifest.IndexType
+	return utils.Dump(collectionConfig, filename)
+}
+
+func writeSnapshotFile(tw *tar.Writer, path string, name string, info os.FileInfo) (*SnapshotFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tw, hash), f)
+	if err != nil {
+		return nil, err
+	}
+	return &SnapshotFile{
+		Path:   name,
+		Size:   size,
+		Sha256: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// extractSnapshot extracts the archive into targetDir and verifies every file against the manifest.
+func extractSnapshot(r io.Reader, targetDir string) (*SnapshotManifest, error) {
+	if !utils.CreateDirPath(targetDir) {
+		return nil, vqerrors.Internal("create dir [%s] failed", targetDir)
+	}
+
+	var manifest *SnapshotManifest
+	checksums := make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, vqerrors.Wrap(vqerrors.CodeInvalidArgument, err, "invalid snapshot archive")
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return nil, vqerrors.InvalidArgument("invalid file path in snapshot: %s", header.Name)
+		}
+
+		if name == SnapshotManifestName {
+			manifest = &SnapshotManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, vqerrors.Wrap(vqerrors.CodeInvalidArgument, err, "invalid snapshot manifest")
+			}
+			continue
+		}
+
+		targetPath := filepath.Join(targetDir, name)
+		f, err := utils.CreatNestedFile(targetPath)
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.New()
+		_, err = io.Copy(io.MultiWriter(f, hash), tr)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		checksums[filepath.ToSlash(name)] = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	if manifest == nil {
+		return nil, vqerrors.InvalidArgument("snapshot manifest not found")
+	}
+	if manifest.Version != SnapshotVersion {
+		return nil, vqerrors.InvalidArgument("unsupported snapshot version %d", manifest.Version)
+	}
+	if len(checksums) != len(manifest.Files) {
+		return nil, vqerrors.InvalidArgument("snapshot file count mismatch, manifest %d, archive %d", len(manifest.Files), len(checksums))
+	}
+	for _, file := range manifest.Files {
+		if checksums[file.Path] != file.Sha256 {
+			return nil, vqerrors.InvalidArgument("snapshot checksum mismatch: %s", file.Path)
+		}
+	}
+	return manifest, nil
+}
@@ -0,0 +1,286 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"vqlite/config"
+	vqerrors "vqlite/errors"
+	"vqlite/utils"
+)
+
+// SnapshotsDirName is the hidden dir under the data path that keeps named snapshots,
+// laid out as <dataPath>/.snapshots/<collection>/<snapshot>/segment_N
+const SnapshotsDirName = ".snapshots"
+
+func collectionSnapshotsPath(collectionName string) string {
+	return utils.Join(config.GlobalConfig.ServiceConfig.DataPath, SnapshotsDirName, collectionName)
+}
+
+func checkSnapshotName(snapshotName string) error {
+	if snapshotName == "" {
+		return vqerrors.InvalidArgument("snapshot name is empty")
+	}
+	if strings.HasPrefix(snapshotName, ".") || strings.ContainsAny(snapshotName, `/\`) {
+		return vqerrors.InvalidArgument("invalid snapshot name [%s]", snapshotName)
+	}
+	return nil
+}
+
+func CreateCollectionSnapshot(collectionName string, snapshotName string) (*SnapshotManifest, error) {
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if err := collection.checkOnDisk("snapshot"); err != nil {
+		return nil, err
+	}
+	if snapshotName == "" {
+		snapshotName = time.Now().Format("20060102150405")
+	}
+	if err := checkSnapshotName(snapshotName); err != nil {
+		return nil, err
+	}
+	return collection.CreateSnapshot(snapshotName)
+}
+
+func ListCollectionSnapshots(collectionName string) ([]SnapshotManifest, error) {
+	if err := CheckCollection(collectionName); err != nil {
+		return nil, err
+	}
+	snapshots := make([]SnapshotManifest, 0)
+	snapshotDirs, err := os.ReadDir(collectionSnapshotsPath(collectionName))
+	if os.IsNotExist(err) {
+		return snapshots, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshotDir := range snapshotDirs {
+		if !snapshotDir.IsDir() || strings.HasPrefix(snapshotDir.Name(), ".") {
+			continue
+		}
+		manifest, err := loadSnapshotManifest(utils.Join(collectionSnapshotsPath(collectionName), snapshotDir.Name()))
+		if err != nil {
+			log.Warn().Err(err).Msgf("load snapshot [%s] manifest error", snapshotDir.Name())
+			continue
+		}
+		snapshots = append(snapshots, *manifest)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt < snapshots[j].CreatedAt
+	})
+	return snapshots, nil
+}
+
+func RestoreCollectionSnapshot(collectionName string, snapshotName string) (*SnapshotManifest, error) {
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if err := collection.checkOnDisk("snapshot restore"); err != nil {
+		return nil, err
+	}
+	if err := checkSnapshotName(snapshotName); err != nil {
+		return nil, err
+	}
+	snapshotPath := utils.Join(collectionSnapshotsPath(collectionName), snapshotName)
+	manifest, err := loadSnapshotManifest(snapshotPath)
+	if err != nil {
+		return nil, vqerrors.NotFound("snapshot [%s] not exists", snapshotName)
+	}
+	if err := collection.RestoreSnapshot(snapshotPath); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func DeleteCollectionSnapshot(collectionName string, snapshotName string) error {
+	if _, err := GetCollection(collectionName); err != nil {
+		return err
+	}
+	if err := checkSnapshotName(snapshotName); err != nil {
+		return err
+	}
+	snapshotPath := utils.Join(collectionSnapshotsPath(collectionName), snapshotName)
+	if !utils.IsDir(snapshotPath) {
+		return vqerrors.NotFound("snapshot [%s] not exists", snapshotName)
+	}
+	return utils.DeleteDir(snapshotPath)
+}
+
+func loadSnapshotManifest(snapshotPath string) (*SnapshotManifest, error) {
+	data, err := os.ReadFile(utils.Join(snapshotPath, SnapshotManifestName))
+	if err != nil {
+		return nil, err
+	}
+	manifest := &SnapshotManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// CreateSnapshot dumps the collection and keeps a copy of its segments as a named snapshot.
+//
+// Segment files are hard linked into the snapshot when the file system supports it, the
+// segments unshare linked files before they are written again.
+//
+// Parameters:
+// - snapshotName: The name of the snapshot, it must be unique in the collection.
+//
+// Returns:
+// - *SnapshotManifest: The manifest of the created snapshot.
+// - error: An error if the snapshot exists or the segment files can not be linked.
+func (c *Collection) CreateSnapshot(snapshotName string) (*SnapshotManifest, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.IsLoaded() {
+		return nil, vqerrors.NotReady("collection [%s] is not loaded", c.Name())
+	}
+	snapshotPath := utils.Join(collectionSnapshotsPath(c.Name()), snapshotName)
+	if utils.Exists(snapshotPath) {
+		return nil, vqerrors.AlreadyExists("snapshot [%s] already exists", snapshotName)
+	}
+
+	if err := c.dumpAll(); err != nil {
+		return nil, err
+	}
+
+	manifest := &SnapshotManifest{
+		Version:    SnapshotVersion,
+		Name:       snapshotName,
+		Collection: c.Name(),
+		Dim:        c.Dim,
+		IndexType:  c.IndexType,
+		Segments:   make([]uint64, 0, len(c.Segments)),
+		CreatedAt:  time.Now().Unix(),
+		Files:      make([]SnapshotFile, 0),
+	}
+
+	// link into a hidden temp dir first, so a partial snapshot is never listed
+	tempPath := utils.Join(collectionSnapshotsPath(c.Name()), "."+snapshotName)
+	_ = utils.DeleteDir(tempPath)
+	for _, seg := range c.Segments {
+		segmentDir := filepath.Base(seg.SegmentConfig.SegmentWorkDir)
+		if err := utils.LinkOrCopyDir(seg.SegmentConfig.SegmentWorkDir, utils.Join(tempPath, segmentDir)); err != nil {
+			_ = utils.DeleteDir(tempPath)
+			log.Error().Err(err).Msgf("create snapshot [%s] of collection [%s] error", snapshotName, c.Name())
+			return nil, err
+		}
+		seg.SetFilesShared()
+		manifest.Segments = append(manifest.Segments, seg.SegmentConfig.SegmentId)
+	}
+	// the config is dumped by rename, so linking it is safe
+	if utils.Exists(c.configFilePath()) {
+		if err := utils.LinkOrCopyFile(c.configFilePath(), utils.Join(tempPath, CollectionConfigFileName)); err != nil {
+			_ = utils.DeleteDir(tempPath)
+			log.Error().Err(err).Msgf("create snapshot [%s] of collection [%s] error", snapshotName, c.Name())
+			return nil, err
+		}
+	}
+	err := filepath.Walk(tempPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(tempPath, path)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, SnapshotFile{Path: filepath.ToSlash(relPath), Size: info.Size()})
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		_ = utils.DeleteDir(tempPath)
+		return nil, err
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		_ = utils.DeleteDir(tempPath)
+		return nil, err
+	}
+	manifestFile, err := utils.CreatNestedFile(utils.Join(tempPath, SnapshotManifestName))
+	if err != nil {
+		_ = utils.DeleteDir(tempPath)
+		return nil, err
+	}
+	_, err = manifestFile.Write(manifestData)
+	manifestFile.Close()
+	if err != nil {
+		_ = utils.DeleteDir(tempPath)
+		return nil, err
+	}
+	if err := os.Rename(tempPath, snapshotPath); err != nil {
+		_ = utils.DeleteDir(tempPath)
+		return nil, err
+	}
+	log.Info().Msgf("create snapshot [%s] of collection [%s] success, segments: %d", snapshotName, c.Name(), len(manifest.Segments))
+	return manifest, nil
+}
+
+// RestoreSnapshot replaces all segments of the collection with the segments kept in snapshotPath.
+//
+// The snapshot is linked into a staging dir, which is swapped with the collection dir, and the
+// new segments are loaded, all while holding the collection lock. Searches see either the old
+// or the new segments. The old segments are released after the swap.
+//
+// Parameters:
+// - snapshotPath: The dir of the snapshot.
+//
+// Returns:
+// - error: An error if the snapshot can not be linked or loaded, the collection is unchanged then.
+func (c *Collection) RestoreSnapshot(snapshotPath string) error {
+	dataPath := config.GlobalConfig.ServiceConfig.DataPath
+	now := time.Now().UnixNano()
+	stagingPath := utils.Join(dataPath, fmt.Sprintf(".restore_%s_%d", c.Name(), now))
+	trashPath := utils.Join(dataPath, fmt.Sprintf(".trash_%s_%d", c.Name(), now))
+
+	if err := utils.LinkOrCopyDir(snapshotPath, stagingPath); err != nil {
+		_ = utils.DeleteDir(stagingPath)
+		return err
+	}
+	_ = os.Remove(utils.Join(stagingPath, SnapshotManifestName))
+
+	c.lock.Lock()
+	if err := os.Rename(c.CollectionWorkDir, trashPath); err != nil {
+		c.lock.Unlock()
+		_ = utils.DeleteDir(stagingPath)
+		return err
+	}
+	if err := os.Rename(stagingPath, c.CollectionWorkDir); err != nil {
+		_ = os.Rename(trashPath, c.CollectionWorkDir)
+		c.lock.Unlock()
+		_ = utils.DeleteDir(stagingPath)
+		return err
+	}
+
+	newSegments, err := c.loadSegmentsFromWorkDir()
+	if err != nil {
+		// roll back to the old segments
+		_ = os.Rename(c.CollectionWorkDir, stagingPath)
+		_ = os.Rename(trashPath, c.CollectionWorkDir)
+		c.lock.Unlock()
+		_ = utils.DeleteDir(stagingPath)
+		log.Error().Err(err).Msgf("restore snapshot of collection [%s] error", c.Name())
+		return err
+	}
+
+	oldSegments := c.swapSegments(newSegments)
+	c.loadConfig()
+	_ = c.setState(CollectionStateLoaded, nil)
+	c.lock.Unlock()
+
+	releaseSegments(oldSegments)
+	if err := utils.DeleteDir(trashPath); err != nil {
+		log.Warn().Err(err).Msgf("delete dir [%s] error", trashPath)
+	}
+	log.Info().Msgf("restore snapshot [%s] of collection [%s] success, segments: %d", snapshotPath, c.Name(), len(newSegments))
+	return nil
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"github.com/rs/zerolog/log"
+	scann "vqlite/engine/go-scann"
+	"vqlite/engine/hnsw"
+	vqerrors "vqlite/errors"
+)
+
+// withDefaults fills the unset parameters of opt with the ones of stored.
+func (opt TrainOpt) withDefaults(stored TrainOpt) TrainOpt {
+	if opt.TrainType == "" {
+		opt.TrainType = stored.TrainType
+	}
+	if opt.Nlist == 0 {
+		opt.Nlist = stored.Nlist
+	}
+	if opt.BruteThreshold == 0 {
+		opt.BruteThreshold = stored.BruteThreshold
+	}
+	if opt.PartitioningTrainSampleRate == 0 {
+		opt.PartitioningTrainSampleRate = stored.PartitioningTrainSampleRate
+	}
+	if opt.HashTrainSampleRate == 0 {
+		opt.HashTrainSampleRate = stored.HashTrainSampleRate
+	}
+	if opt.M == 0 {
+		opt.M = stored.M
+	}
+	if opt.EfConstruction == 0 {
+		opt.EfConstruction = stored.EfConstruction
+	}
+	return opt
+}
+
+func (opt TrainOpt) check() error {
+	if !scann.ValidTrainType(opt.TrainType) {
+		return vqerrors.InvalidArgument("unknown train_type [%s], must be %s, %s or %s",
+			opt.TrainType, scann.TrainTypeDefault, scann.TrainTypeNew, scann.TrainTypeAdd)
+	}
+	if opt.TrainType == scann.TrainTypeAdd && opt.Nlist > 0 {
+		return vqerrors.InvalidArgument("nlist is only used by new trainings, train_type [%s] keeps the partitions", opt.TrainType)
+	}
+	if opt.PartitioningTrainSampleRate < 0 || opt.PartitioningTrainSampleRate > 1 {
+		return vqerrors.InvalidArgument("partitioning_train_sample_rate %v must be in [0, 1], 0 uses the default", opt.PartitioningTrainSampleRate)
+	}
+	if opt.HashTrainSampleRate < 0 || opt.HashTrainSampleRate > 1 {
+		return vqerrors.InvalidArgument("hash_train_sample_rate %v must be in [0, 1], 0 uses the default", opt.HashTrainSampleRate)
+	}
+	if opt.M != 0 && (opt.M < hnsw.MinM || opt.M > hnsw.MaxM) {
+		return vqerrors.InvalidArgument("m %d must be in [%d, %d], 0 uses the default", opt.M, hnsw.MinM, hnsw.MaxM)
+	}
+	if opt.EfConstruction < 0 || opt.EfConstruction > hnsw.MaxEfConstruction {
+		return vqerrors.InvalidArgument("ef_construction %d must be in [0, %d], 0 uses the default", opt.EfConstruction, hnsw.MaxEfConstruction)
+	}
+	return nil
+}
+
+// checkIndexType checks that opt only sets parameters used by indexType.
+func (opt TrainOpt) checkIndexType(indexType string) error {
+	if indexType == scann.IndexTypeFAISS && opt.HashTrainSampleRate > 0 {
+		return vqerrors.InvalidArgument("hash_train_sample_rate is not used by index type [%s]", indexType)
+	}
+	if indexType == hnsw.IndexType && (opt.Nlist > 0 || opt.BruteThreshold > 0 || opt.PartitioningTrainSampleRate > 0 || opt.HashTrainSampleRate > 0) {
+		return vqerrors.InvalidArgument("index type [%s] has no training, only m and ef_construction are used", indexType)
+	}
+	if indexType != hnsw.IndexType && (opt.M > 0 || opt.EfConstruction > 0) {
+		return vqerrors.InvalidArgument("m and ef_construction are only used by index type [%s]", hnsw.IndexType)
+	}
+	return nil
+}
+
+func (opt TrainOpt) indexParams() scann.IndexParams {
+	return scann.IndexParams{
+		BruteThreshold:              opt.BruteThreshold,
+		PartitioningTrainSampleRate: opt.PartitioningTrainSampleRate,
+		HashTrainSampleRate:         opt.HashTrainSampleRate,
+		M:                           opt.M,
+		EfConstruction:              opt.EfConstruction,
+	}
+}
+
+func (opt TrainOpt) trainParams() scann.TrainParams {
+	return scann.TrainParams{Type: opt.TrainType, Nlist: opt.Nlist}
+}
+
+// segmentTrainOpt returns the parameters recorded in the config of a segment by its last training.
+func segmentTrainOpt(segmentConfig SegmentConfig) TrainOpt {
+	return TrainOpt{
+		TrainType:                   segmentConfig.TrainParams.Type,
+		Nlist:                       segmentConfig.TrainParams.Nlist,
+		BruteThreshold:              segmentConfig.IndexParams.BruteThreshold,
+		PartitioningTrainSampleRate: segmentConfig.IndexParams.PartitioningTrainSampleRate,
+		HashTrainSampleRate:         segmentConfig.IndexParams.HashTrainSampleRate,
+		M:                           segmentConfig.IndexParams.M,
+		EfConstruction:              segmentConfig.IndexParams.EfConstruction,
+	}
+}
+
+// SetTrainOpt stores opt as the training parameters of the collection, used by trainings which leave them unset.
+func SetTrainOpt(collectionName string, opt TrainOpt) (*Collection, error) {
+	collection, err := GetLoadedCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if err := opt.check(); err != nil {
+		return nil, err
+	}
+	collectionConfig := collection.Config()
+	if err := opt.checkIndexType(collectionConfig.IndexType); err != nil {
+		return nil, err
+	}
+	collectionConfig.TrainOpt = opt
+	if err := collection.setConfig(collectionConfig); err != nil {
+		return nil, err
+	}
+	log.Info().Msgf("set train opt of collection [%s] to %+v", collectionName, opt)
+	return collection, nil
+}
@@ -1,12 +1,24 @@
 package core
 
-import scann "vqlite/engine/go-scann"
+import (
+	scann "vqlite/engine/go-scann"
+	vqerrors "vqlite/errors"
+)
 
 type QueryOpt struct {
 	TopK    int `json:"topk"`
 	NProbe  int `json:"nprobe"`
 	Reorder int `json:"reorder"`
 	Timeout int `json:"timeout"`
+	// NProbeRatio probes this fraction of the nlist of every segment, used when NProbe is 0
+	NProbeRatio float64 `json:"nprobe_ratio"`
+	// AllowPartial returns the results of the healthy segments instead of failing when some segments
+	// fail or do not answer in time
+	AllowPartial bool `json:"allow_partial"`
+	// Debug returns how every segment answered with the results
+	Debug bool `json:"debug"`
+	// EfSearch is the number of candidates searched in the graph of a HNSW index, at least topk
+	EfSearch int `json:"ef_search"`
 }
 
 type Metadata struct {
@@ -16,9 +28,14 @@ type Metadata struct {
 
 // Http Request and Response struct
 
+// CreateCollectionRequest creates a collection, Storage memory keeps it off disk and TTL drops it
+// once not accessed for TTL seconds, 0 keeps it until dropped. IndexType is ScaNN or FAISS, default ScaNN.
 type CreateCollectionRequest struct {
-	Name string `json:"name"`
-	Dim  int    `json:"dim"`
+	Name      string `json:"name"`
+	Dim       int    `json:"dim"`
+	Storage   string `json:"storage"`
+	TTL       int    `json:"ttl"`
+	IndexType string `json:"index_type"`
 }
 
 type BatchAddDocumentsRequest struct {
@@ -42,6 +59,36 @@ type UpdateDocumentMetadataRequest struct {
 type TrainRequest struct {
 	Threads     int  `json:"threads"`
 	IgnoreCheck bool `json:"ignore_check"`
+	TrainOpt
+}
+
+// TrainOpt are the parameters of the index training, zero values use the ones stored with the
+// collection, then the engine defaults.
+type TrainOpt struct {
+	TrainType                   string  `json:"train_type"` // default, new or add
+	Nlist                       uint32  `json:"nlist"`      // only used by new trainings
+	BruteThreshold              uint64  `json:"brute_threshold"`
+	PartitioningTrainSampleRate float32 `json:"partitioning_train_sample_rate"`
+	HashTrainSampleRate         float32 `json:"hash_train_sample_rate"`
+	// M and EfConstruction build the graph of new HNSW segments, the graph of a segment never changes them
+	M              int `json:"m"`
+	EfConstruction int `json:"ef_construction"`
+}
+
+type RenameCollectionRequest struct {
+	Name string `json:"name"`
+}
+
+type CloneCollectionRequest struct {
+	Name string `json:"name"`
+}
+
+type SetAliasRequest struct {
+	Collection string `json:"collection"`
+}
+
+type CreateSnapshotRequest struct {
+	Name string `json:"name"`
 }
 
 type SearchRequest struct {
@@ -49,6 +96,45 @@ type SearchRequest struct {
 	Opt     QueryOpt    `json:"opt"`
 }
 
+// SearchResponse is the merged result of the search of every segment.
+type SearchResponse struct {
+	Results [][]SearchResult `json:"results"`
+	// Partial is set when some segments failed or did not answer in time and AllowPartial was set,
+	// FailedSegments are these segments
+	Partial        bool            `json:"partial"`
+	FailedSegments []FailedSegment `json:"failed_segments,omitempty"`
+	Debug          *SearchDebug    `json:"debug,omitempty"`
+}
+
+// SearchDebug explains a search with QueryOpt.Debug, Opt is the QueryOpt used after the defaults.
+type SearchDebug struct {
+	Opt      QueryOpt             `json:"opt"`
+	TookMs   float64              `json:"took_ms"`
+	Segments []SegmentSearchDebug `json:"segments"`
+}
+
+// SegmentSearchDebug is how a segment answered a search. Candidates is the number of results of the
+// segment for all query vectors, Merged of them are in the topk results after merging all segments and
+// DroppedDeleted were in the topk but left out since their document is deleted. IsBrute is set when
+// the index searched all vectors instead of nprobe partitions.
+type SegmentSearchDebug struct {
+	SegmentId      uint64  `json:"segment_id"`
+	TookMs         float64 `json:"took_ms"`
+	IsBrute        bool    `json:"is_brute"`
+	NProbe         int     `json:"nprobe"`
+	Candidates     int     `json:"candidates"`
+	Merged         int     `json:"merged"`
+	DroppedDeleted int     `json:"dropped_deleted"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// FailedSegment is a segment left out of partial search results, with the reason.
+type FailedSegment struct {
+	SegmentId uint64        `json:"segment_id"`
+	Code      vqerrors.Code `json:"code"`
+	Error     string        `json:"error"`
+}
+
 type SearchResult struct {
 	Vqid     string                 `json:"vqid"`
 	Score    float32                `json:"score"`
@@ -68,18 +154,49 @@ type SegmentStatistics struct {
 	SegmentId       uint64                `json:"segment_id"`
 	Sealed          bool                  `json:"sealed"`
 	Dim             int                   `json:"dim"`
+	Dirty           bool                  `json:"dirty"`
 	IndexStatistics scann.IndexStatistics `json:"index_statistics"`
 	VectorCount     int64                 `json:"vector_count"`
 	DocCount        int64                 `json:"doc_count"`
+	TrainOpt        TrainOpt              `json:"train_opt"` // parameters of the last training
+	// TrainDelta is the ratio of vectors added since the last full training to the ones it trained on,
+	// TrainDrift the cosine distance of their means, both 0 without a trained index
+	TrainDelta float64 `json:"train_delta"`
+	TrainDrift float64 `json:"train_drift"`
+}
+
+// CollectionLoadStatus the load state of a collection, times are unix seconds and 0 if not happened yet
+type CollectionLoadStatus struct {
+	State          string `json:"state"`
+	Ready          bool   `json:"ready"`
+	StateChangedAt int64  `json:"state_changed_at"`
+	LoadStartedAt  int64  `json:"load_started_at"`
+	LoadedAt       int64  `json:"loaded_at"`
+	LoadDuration   int64  `json:"load_duration_ms"`
+	LastError      string `json:"last_error"`
+	LastErrorAt    int64  `json:"last_error_at"`
 }
 
 type CollectionStatistics struct {
-	CollectionName string              `json:"collection_name"`
-	Segments       []SegmentStatistics `json:"segments"`
-	SegmentCount   uint64              `json:"segment_count"`
-	TotalIndexSize int64               `json:"total_index_size"`
-	VectorCount    uint64              `json:"vector_count"`
-	DocCount       uint64              `json:"doc_count"`
+	CollectionName string               `json:"collection_name"`
+	IndexType      string               `json:"index_type"`
+	Aliases        []string             `json:"aliases"`
+	LoadStatus     CollectionLoadStatus `json:"load_status"`
+	LastAccess     int64                `json:"last_access"`
+	Dirty          bool                 `json:"dirty"`         // any segment changed since the last flush
+	LastFlushAt    int64                `json:"last_flush_at"` // unix seconds, 0 if never flushed
+	LastFlushError string               `json:"last_flush_error"`
+	Segments       []SegmentStatistics  `json:"segments"`
+	SegmentCount   uint64               `json:"segment_count"`
+	TotalIndexSize int64                `json:"total_index_size"`
+	VectorCount    uint64               `json:"vector_count"`
+	DocCount       uint64               `json:"doc_count"`
+	MemorySize     int64                `json:"memory_size"` // estimated size of vectors in memory
+	SearchOpt      QueryOpt             `json:"search_opt"`
+	Tuning         *SearchTuning        `json:"tuning,omitempty"`
+	TrainOpt       TrainOpt             `json:"train_opt"`
+	Storage        string               `json:"storage"`
+	TTL            int64                `json:"ttl"`
 }
 
 type VQLiteStatistics struct {
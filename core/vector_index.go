@@ -0,0 +1,113 @@
+package core
+
+import (
+	"context"
+	scann "vqlite/engine/go-scann"
+	"vqlite/engine/hnsw"
+	vqerrors "vqlite/errors"
+)
+
+// VectorIndex is the index of a segment, a ScaNN or FAISS index of the engine or a HNSW graph.
+type VectorIndex interface {
+	Search(ctx context.Context, xq []float32, opt QueryOpt) ([][]scann.VidScore, error)
+	AddWithIDs(vectors [][]float32, vids []int64) error
+	// Delete tombstones the vectors whose vid matches, an index without tombstones deletes nothing,
+	// the results of deleted documents are dropped when merging then
+	Delete(match func(vid int64) bool) int
+	Statistics() scann.IndexStatistics
+	Train(params scann.TrainParams) error
+	Dump() error
+	Destroy()
+	WorkDir() string
+}
+
+// newVectorIndex creates the index of indexParams.IndexType in workDir, loading what was dumped there.
+func newVectorIndex(workDir string, dim int, indexId uint64, indexParams scann.IndexParams) (VectorIndex, error) {
+	inMemory := indexParams.Storage == scann.StorageMemory
+	if indexParams.IndexType == hnsw.IndexType {
+		index, err := hnsw.New(workDir, dim, hnsw.Params{M: indexParams.M, EfConstruction: indexParams.EfConstruction}, inMemory)
+		if err != nil {
+			return nil, err
+		}
+		return &hnswIndex{Index: index, indexId: indexId}, nil
+	}
+	index, err := scann.NewScaNNIndex(workDir, dim, indexId, indexParams)
+	if err != nil {
+		return nil, err
+	}
+	return &scannIndex{ScaNNIndex: index}, nil
+}
+
+// validIndexType reports whether indexType is an index type of the engine or HNSW, empty is the default.
+func validIndexType(indexType string) bool {
+	return scann.ValidIndexType(indexType) || indexType == hnsw.IndexType
+}
+
+type scannIndex struct {
+	*scann.ScaNNIndex
+}
+
+func (s *scannIndex) Search(ctx context.Context, xq []float32, opt QueryOpt) ([][]scann.VidScore, error) {
+	return s.ScaNNIndex.Search(ctx, xq, opt.TopK, opt.NProbe, opt.Reorder)
+}
+
+func (s *scannIndex) Delete(match func(vid int64) bool) int {
+	return 0
+}
+
+func (s *scannIndex) WorkDir() string {
+	return s.IndexWorkDir
+}
+
+// hnswIndex is a HNSW graph, every vector is in the graph once added, so it is never trained.
+type hnswIndex struct {
+	*hnsw.Index
+	indexId uint64
+}
+
+func (h *hnswIndex) Search(ctx context.Context, xq []float32, opt QueryOpt) ([][]scann.VidScore, error) {
+	results, err := h.Index.Search(ctx, xq, opt.TopK, opt.EfSearch)
+	if err != nil {
+		return nil, err
+	}
+	res := make([][]scann.VidScore, len(results))
+	for i, queryResults := range results {
+		for _, result := range queryResults {
+			res[i] = append(res[i], scann.VidScore{Vid: result.Vid, Score: result.Score, From: h.indexId})
+		}
+	}
+	return res, nil
+}
+
+func (h *hnswIndex) AddWithIDs(vectors [][]float32, vids []int64) error {
+	return h.Index.Add(vectors, vids)
+}
+
+// Statistics reports every vector as indexed, deleted ones included, the graph has no partitions.
+func (h *hnswIndex) Statistics() scann.IndexStatistics {
+	stat := h.Index.Statistics()
+	status := scann.IndexStateReady
+	if stat.Size == 0 {
+		status = scann.IndexStateNoIndex
+	}
+	return scann.IndexStatistics{
+		DatasetSize: stat.Size,
+		VidSize:     stat.Size,
+		IndexSize:   stat.Size,
+		VecDim:      int32(stat.Dim),
+		Status:      status,
+		IndexType:   hnsw.IndexType,
+	}
+}
+
+func (h *hnswIndex) Train(params scann.TrainParams) error {
+	return vqerrors.InvalidArgument("index type [%s] has no training", hnsw.IndexType)
+}
+
+func (h *hnswIndex) Destroy() {
+	h.Index.Release()
+}
+
+func (h *hnswIndex) WorkDir() string {
+	return h.Index.WorkDir
+}
@@ -0,0 +1,110 @@
+package go_scann
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	vqerrors "vqlite/errors"
+)
+
+const (
+	datasetsFileName = "datasets.vql"
+	vidsFileName     = "vids.vql"
+)
+
+// Dataset reads the vectors added to an index from its work dir, the datasets file holds the
+// vectors back to back as little endian float32 and the vids file their ids as little endian int64.
+type Dataset struct {
+	Dim      int
+	datasets *os.File
+	vids     *os.File
+	size     int
+}
+
+// OpenDataset opens the dataset of the index in indexWorkDir, the files must hold the same number of vectors.
+func OpenDataset(indexWorkDir string, dim int) (*Dataset, error) {
+	datasets, err := os.Open(filepath.Join(indexWorkDir, datasetsFileName))
+	if err != nil {
+		return nil, vqerrors.Wrap(vqerrors.CodeNotFound, err, "open dataset of index [%s] error", indexWorkDir)
+	}
+	vids, err := os.Open(filepath.Join(indexWorkDir, vidsFileName))
+	if err != nil {
+		datasets.Close()
+		return nil, vqerrors.Wrap(vqerrors.CodeNotFound, err, "open vids of index [%s] error", indexWorkDir)
+	}
+	d := &Dataset{Dim: dim, datasets: datasets, vids: vids}
+
+	datasetsInfo, err := datasets.Stat()
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	vidsInfo, err := vids.Stat()
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	vectorBytes := int64(dim) * 4
+	if dim <= 0 || datasetsInfo.Size()%vectorBytes != 0 || vidsInfo.Size()%8 != 0 || datasetsInfo.Size()/vectorBytes != vidsInfo.Size()/8 {
+		d.Close()
+		return nil, vqerrors.Internal("dataset of index [%s] has %d bytes of vectors with dim %d and %d bytes of vids, which do not match",
+			indexWorkDir, datasetsInfo.Size(), dim, vidsInfo.Size())
+	}
+	d.size = int(vidsInfo.Size() / 8)
+	return d, nil
+}
+
+// Len returns the number of vectors.
+func (d *Dataset) Len() int {
+	return d.size
+}
+
+// Vector returns the i-th vector.
+func (d *Dataset) Vector(i int) ([]float32, error) {
+	if i < 0 || i >= d.size {
+		return nil, vqerrors.InvalidArgument("vector %d out of range [0, %d)", i, d.size)
+	}
+	buf := make([]byte, d.Dim*4)
+	if _, err := d.datasets.ReadAt(buf, int64(i)*int64(len(buf))); err != nil {
+		return nil, err
+	}
+	vec := make([]float32, d.Dim)
+	decodeFloat32s(buf, vec)
+	return vec, nil
+}
+
+// Scan calls fn with every vector and its vid in order, vec is reused between calls.
+func (d *Dataset) Scan(fn func(vid int64, vec []float32) error) error {
+	datasets := bufio.NewReaderSize(io.NewSectionReader(d.datasets, 0, int64(d.size)*int64(d.Dim)*4), 1<<20)
+	vids := bufio.NewReader(io.NewSectionReader(d.vids, 0, int64(d.size)*8))
+	buf := make([]byte, d.Dim*4)
+	vidBuf := make([]byte, 8)
+	vec := make([]float32, d.Dim)
+	for i := 0; i < d.size; i++ {
+		if _, err := io.ReadFull(datasets, buf); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(vids, vidBuf); err != nil {
+			return err
+		}
+		decodeFloat32s(buf, vec)
+		if err := fn(int64(binary.LittleEndian.Uint64(vidBuf)), vec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dataset) Close() {
+	d.datasets.Close()
+	d.vids.Close()
+}
+
+func decodeFloat32s(buf []byte, vec []float32) {
+	for j := range vec {
+		vec[j] = math.Float32frombits(binary.LittleEndian.Uint32(buf[j*4:]))
+	}
+}
@@ -8,10 +8,12 @@ package go_scann
 // #include <stdlib.h>
 import "C"
 import (
+	"context"
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"sync"
 	"unsafe"
+	vqerrors "vqlite/errors"
 	"vqlite/utils"
 	"vqlite/utils/conc"
 )
@@ -25,6 +27,84 @@ type IndexStatistics struct {
 	BruteThreshold int64  `json:"brute_threshold"`
 	IsBrute        bool   `json:"is_brute"`
 	Status         string `json:"status"`
+	IndexType      string `json:"index_type"`
+}
+
+// IndexParams are the parameters an index is created with, zero values use the engine defaults.
+type IndexParams struct {
+	BruteThreshold              uint64  // indexes with fewer vectors are searched by brute force, default 4096
+	PartitioningTrainSampleRate float32 // share of the dataset sampled to train the partitions, default 0.2
+	HashTrainSampleRate         float32 // share of the dataset sampled to train the hashing, default 0.1
+	Storage                     string  // StorageFile or StorageMemory, empty is StorageFile
+	IndexType                   string  // IndexTypeScaNN or IndexTypeFAISS, empty is IndexTypeScaNN
+	// M and EfConstruction build the graph of a HNSW index, they are not used by the engine
+	M              int
+	EfConstruction int
+}
+
+// Index types of the engine. A FAISS index has no reordering, its searches return the nearest
+// of the probed partitions directly, and it does not sample the dataset to train a hashing.
+const (
+	IndexTypeScaNN = "ScaNN"
+	IndexTypeFAISS = "FAISS"
+)
+
+var indexTypes = map[string]C.index_type_t{
+	"":             C.index_type_t(C.INDEX_TYPE_SCANN),
+	IndexTypeScaNN: C.index_type_t(C.INDEX_TYPE_SCANN),
+	IndexTypeFAISS: C.index_type_t(C.INDEX_TYPE_FAISS),
+}
+
+// ValidIndexType reports whether indexType is an index type of the engine, empty is the default.
+func ValidIndexType(indexType string) bool {
+	_, ok := indexTypes[indexType]
+	return ok
+}
+
+// Storage types of the engine, an index with StorageMemory never touches its work dir.
+const (
+	StorageFile   = "file"
+	StorageMemory = "memory"
+)
+
+var storageTypes = map[string]C.storage_type_t{
+	"":            C.storage_type_t(C.STORAGE_FILE),
+	StorageFile:   C.storage_type_t(C.STORAGE_FILE),
+	StorageMemory: C.storage_type_t(C.STORAGE_MEMORY),
+}
+
+// ValidStorage reports whether storage is a storage type of the engine, empty is the default.
+func ValidStorage(storage string) bool {
+	_, ok := storageTypes[storage]
+	return ok
+}
+
+// Train types of the engine, TrainTypeDefault lets the engine choose.
+const (
+	TrainTypeDefault = "default"
+	TrainTypeNew     = "new"
+	TrainTypeAdd     = "add"
+)
+
+var trainTypes = map[string]C.train_type_t{
+	"":               C.train_type_t(C.TRAIN_TYPE_DEFAULT),
+	TrainTypeDefault: C.train_type_t(C.TRAIN_TYPE_DEFAULT),
+	TrainTypeNew:     C.train_type_t(C.TRAIN_TYPE_NEW),
+	TrainTypeAdd:     C.train_type_t(C.TRAIN_TYPE_ADD),
+}
+
+// TrainParams are the parameters of a training, Nlist 0 uses upNearestPower2(sqrt(n)) and
+// is only used by new trainings, Threads 0 uses all cores.
+type TrainParams struct {
+	Type    string
+	Nlist   uint32
+	Threads int
+}
+
+// ValidTrainType reports whether trainType is a train type of the engine, empty is the default.
+func ValidTrainType(trainType string) bool {
+	_, ok := trainTypes[trainType]
+	return ok
 }
 
 type VidScore struct {
@@ -38,6 +118,8 @@ type ScaNNIndex struct {
 	Dim          int
 	IndexWorkDir string
 	IndexId      uint64
+	InMemory     bool
+	IndexType    string
 	vdbCRwLock   sync.RWMutex
 }
 
@@ -89,8 +171,47 @@ var CRetCodeMap = map[C.ret_code_t]string{
 	C.ret_code_t(C.RET_CODE_NOINIT):       RetCodeNoInit,
 }
 
-func NewScaNNIndex(indexWorkDir string, dimIn int, indexId uint64) (vdb *ScaNNIndex, err error) {
-	if !utils.Exists(indexWorkDir) {
+// retCodeErrorCodes maps the ret codes of the engine to error codes, the others are internal errors.
+var retCodeErrorCodes = map[string]vqerrors.Code{
+	RetCodeNoReady:   vqerrors.CodeNotReady,
+	RetCodeNoInit:    vqerrors.CodeNotReady,
+	RetCodeMemoryErr: vqerrors.CodeResourceExhausted,
+	RetCodeDataErr:   vqerrors.CodeInvalidArgument,
+}
+
+// newRetCodeError returns the error for a failed engine call, keeping the ret code as EngineCode.
+func newRetCodeError(op string, exeCode C.ret_code_t) *vqerrors.Error {
+	retCode, ok := CRetCodeMap[exeCode]
+	if !ok {
+		retCode = RetCodeUnknown
+	}
+	code, ok := retCodeErrorCodes[retCode]
+	if !ok {
+		code = vqerrors.CodeInternal
+	}
+	return &vqerrors.Error{
+		Code:       code,
+		Message:    fmt.Sprintf("%s failed, exeCode: %v, msg: %s", op, exeCode, retCode),
+		EngineCode: retCode,
+	}
+}
+
+func NewScaNNIndex(indexWorkDir string, dimIn int, indexId uint64, params IndexParams) (vdb *ScaNNIndex, err error) {
+	storageType, ok := storageTypes[params.Storage]
+	if !ok {
+		err = vqerrors.InvalidArgument("unknown storage [%s]", params.Storage)
+		return
+	}
+	indexType, ok := indexTypes[params.IndexType]
+	if !ok {
+		err = vqerrors.InvalidArgument("unknown index type [%s]", params.IndexType)
+		return
+	}
+	if params.IndexType == "" {
+		params.IndexType = IndexTypeScaNN
+	}
+	inMemory := params.Storage == StorageMemory
+	if !inMemory && !utils.Exists(indexWorkDir) {
 		utils.CreateDirPath(indexWorkDir)
 	}
 	workDirC := C.CString(indexWorkDir)
@@ -98,24 +219,32 @@ func NewScaNNIndex(indexWorkDir string, dimIn int, indexId uint64) (vdb *ScaNNIn
 	// config
 	var vqliteConfig C.index_config_t
 	vqliteConfig.dim_ = C.uint32_t(dimIn)
-	vqliteConfig.brute_threshold_ = C.uint64_t(0) // 0 means default value 4096
-	vqliteConfig.index_type_ = C.index_type_t(C.INDEX_TYPE_SCANN)
-	vqliteConfig.storage_type_ = C.storage_type_t(C.STORAGE_FILE) // storage type: memory or file
+	vqliteConfig.brute_threshold_ = C.uint64_t(params.BruteThreshold) // 0 means default value 4096
+	vqliteConfig.index_type_ = indexType
+	vqliteConfig.storage_type_ = storageType // storage type: memory or file
 	vqliteConfig.partitioning_train_sample_rate_ = C.float(0.2)
+	if params.PartitioningTrainSampleRate > 0 {
+		vqliteConfig.partitioning_train_sample_rate_ = C.float(params.PartitioningTrainSampleRate)
+	}
 	vqliteConfig.hash_train_sample_rate_ = C.float(0.1)
+	if params.HashTrainSampleRate > 0 {
+		vqliteConfig.hash_train_sample_rate_ = C.float(params.HashTrainSampleRate)
+	}
 	// init index
 	vdbC := C.vqindex_init(workDirC, vqliteConfig)
 	if vdbC == nil {
-		err = fmt.Errorf("failed to create index")
+		err = vqerrors.Internal("failed to create index")
 		return
 	} else {
-		log.Info().Msgf("created index success indexWorkDir:%s, Dim: %v, indexId, %v", indexWorkDir, dimIn, indexId)
+		log.Info().Msgf("created index success indexWorkDir:%s, Dim: %v, indexId, %v, inMemory: %v, indexType: %s", indexWorkDir, dimIn, indexId, inMemory, params.IndexType)
 	}
 	vdb = &ScaNNIndex{
 		vdbC:         vdbC,
 		Dim:          dimIn,
 		IndexWorkDir: indexWorkDir,
 		IndexId:      indexId,
+		InMemory:     inMemory,
+		IndexType:    params.IndexType,
 	}
 
 	return
@@ -135,17 +264,17 @@ func (vdb *ScaNNIndex) Destroy() {
 
 }
 
-func (vdb *ScaNNIndex) Search(xq []float32, k int, nprobe int, reorder int) ([][]VidScore, error) {
-	vdb.vdbCRwLock.RLock()
-	defer vdb.vdbCRwLock.RUnlock()
-
-	if vdb.vdbC == nil {
-		return nil, fmt.Errorf("index not initialized")
-	}
-
+// Search returns the k nearest vectors of every query vector in xq.
+// The search in the engine can not be interrupted, when ctx is done before it returns the
+// search is left running in the pool and ctx.Err() is returned. Searches still queued in the
+// pool when ctx is done are skipped.
+func (vdb *ScaNNIndex) Search(ctx context.Context, xq []float32, k int, nprobe int, reorder int) ([][]VidScore, error) {
 	nq := len(xq) / vdb.Dim
 	if nq < 1 {
-		return nil, fmt.Errorf("invalid xq size")
+		return nil, vqerrors.InvalidArgument("invalid xq size")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	var searchParams C.params_search_t
@@ -154,16 +283,35 @@ func (vdb *ScaNNIndex) Search(xq []float32, k int, nprobe int, reorder int) ([][
 	searchParams.nprobe_ = C.uint32_t(nprobe)
 	searchResult := make([]C.result_search_t, nq*k)
 
-	exeCodeC, _ := conc.GetSQPool().Submit(func() (any, error) {
+	future := conc.GetSQPool().Submit(func() (any, error) {
+		// hold the lock in the pool, so the index is not destroyed under an abandoned search
+		vdb.vdbCRwLock.RLock()
+		defer vdb.vdbCRwLock.RUnlock()
+		if vdb.vdbC == nil {
+			return nil, vqerrors.NotReady("index not initialized")
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		exeCode := C.vqindex_search(vdb.vdbC, (*C.float)(&xq[0]), C.int(len(xq)), (*C.result_search_t)(&searchResult[0]), searchParams)
 		return exeCode, nil
-	}).Await()
+	})
+	select {
+	case <-future.Inner():
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	exeCodeC, err := future.Await()
+	if err != nil {
+		return nil, err
+	}
 	exeCode := exeCodeC.(C.ret_code_t)
 	//exeCode := C.vqindex_search(vdb.vdbC, (*C.float)(&xq[0]), C.int(len(xq)), (*C.result_search_t)(&searchResult[0]), searchParams)
 	if exeCode != 0 {
 		//log.Errorf("search failed")
-		log.Error().Msgf("search failed, exeCode: %v, msg: %s", exeCode, CRetCodeMap[exeCode])
-		return nil, fmt.Errorf("search failed")
+		err := newRetCodeError("search", exeCode)
+		log.Error().Err(err).Msg("search error")
+		return nil, err
 	}
 	res := make([][]VidScore, nq)
 
@@ -182,16 +330,21 @@ func (vdb *ScaNNIndex) Search(xq []float32, k int, nprobe int, reorder int) ([][
 	return res, nil
 }
 
-func (vdb *ScaNNIndex) AddWithIDs(vectors [][]float32, vids []int64) bool {
+func (vdb *ScaNNIndex) AddWithIDs(vectors [][]float32, vids []int64) error {
 	vdb.vdbCRwLock.Lock()
 	defer vdb.vdbCRwLock.Unlock()
 
+	if vdb.vdbC == nil {
+		return vqerrors.NotReady("index not initialized")
+	}
+
 	flattenedVectors := utils.FlattenFloat32Slice(vectors)
 
 	nb := len(vids)
-	if len(flattenedVectors) != nb*vdb.Dim {
-		log.Error().Msgf("invalid length of vectors, want %v, have %v, nb %v, vdb.Dim %v ", nb*vdb.Dim, len(flattenedVectors), nb, vdb.Dim)
-		return false
+	if nb == 0 || len(flattenedVectors) != nb*vdb.Dim {
+		err := vqerrors.InvalidArgument("invalid length of vectors, want %v, have %v, nb %v, dim %v", nb*vdb.Dim, len(flattenedVectors), nb, vdb.Dim)
+		log.Error().Err(err).Msg("add error")
+		return err
 	}
 	exeCodeC, _ := conc.GetDynamicPool().Submit(func() (any, error) {
 		exeCode := C.vqindex_add(vdb.vdbC, (*C.float)(&flattenedVectors[0]), C.uint64_t(nb*vdb.Dim), (*C.int64_t)(&vids[0]))
@@ -201,11 +354,12 @@ func (vdb *ScaNNIndex) AddWithIDs(vectors [][]float32, vids []int64) bool {
 	exeCode := exeCodeC.(C.ret_code_t)
 	//exeCode := C.vqindex_add(vdb.vdbC, (*C.float)(&flattenedVectors[0]), C.uint64_t(nb*vdb.Dim), (*C.int64_t)(&vids[0]))
 	if exeCode != 0 {
-		log.Error().Msgf("add failed, exeCode: %v,  msg: %s", exeCode, CRetCodeMap[exeCode])
-		return false
+		err := newRetCodeError("add", exeCode)
+		log.Error().Err(err).Msg("add error")
+		return err
 	}
 	log.Info().Msgf("AddWithIDs success, nb %v", nb)
-	return true
+	return nil
 }
 
 func (vdb *ScaNNIndex) Statistics() IndexStatistics {
@@ -252,47 +406,62 @@ func (vdb *ScaNNIndex) Statistics() IndexStatistics {
 		BruteThreshold: bruteThreshold,
 		IsBrute:        isBrute,
 		Status:         status,
+		IndexType:      vdb.IndexType,
 	}
 	return stat
 }
 
-func (vdb *ScaNNIndex) Train(numThreads int) error {
+// Train trains the index, a file index is dumped to its work dir by the engine. An in-memory
+// index is trained in place, the read lock keeps it from being destroyed while training.
+func (vdb *ScaNNIndex) Train(params TrainParams) error {
 	statistics := vdb.Statistics()
-	trainType := C.train_type_t(C.TRAIN_TYPE_DEFAULT) // 0 TRAIN_TYPE_DEFAULT, 1 TRAIN_TYPE_NEW, 2 TRAIN_TYPE_ADD
-	trainNlist := C.uint32_t(0)                       // 0 means use default value upNearestPower2(sqrt(n))
-	trainNthreads := C.int32_t(numThreads)            // 0 means enable all cores
+	trainType, ok := trainTypes[params.Type] // 0 TRAIN_TYPE_DEFAULT, 1 TRAIN_TYPE_NEW, 2 TRAIN_TYPE_ADD
+	if !ok {
+		return vqerrors.InvalidArgument("train failed, unknown train type [%s]", params.Type)
+	}
+	trainNlist := C.uint32_t(params.Nlist)     // 0 means use default value upNearestPower2(sqrt(n))
+	trainNthreads := C.int32_t(params.Threads) // 0 means enable all cores
 
 	if statistics.DatasetSize == 0 {
-		errMsg := fmt.Errorf("train failed, dataset size is 0")
+		errMsg := vqerrors.InvalidArgument("train failed, dataset size is 0")
 		log.Error().Err(errMsg)
 		return errMsg
 	}
 
 	if statistics.DatasetSize == statistics.IndexSize {
-		errMsg := fmt.Errorf("train failed, dataset size is equal to index size")
+		errMsg := vqerrors.InvalidArgument("train failed, dataset size is equal to index size")
 		log.Error().Err(errMsg)
 		return errMsg
 	}
 
+	vdb.vdbCRwLock.RLock()
+	defer vdb.vdbCRwLock.RUnlock()
+	if vdb.vdbC == nil {
+		return vqerrors.NotReady("index not initialized")
+	}
 	// train and dump index
 	exeCode := C.vqindex_train(vdb.vdbC, trainType, trainNlist, trainNthreads)
 	if exeCode != 0 {
-		errMsg := fmt.Errorf("train failed, exeCode: %v, msg: %s", exeCode, CRetCodeMap[exeCode])
+		errMsg := newRetCodeError("train", exeCode)
 		log.Error().Err(errMsg)
 		return errMsg
 	} else {
-		log.Info().Msgf("train success, exeCode: %v, msg: %s", exeCode, CRetCodeMap[exeCode])
+		log.Info().Msgf("train success, params: %+v, exeCode: %v, msg: %s", params, exeCode, CRetCodeMap[exeCode])
 	}
 	return nil
 }
 
+// Dump writes the index to its work dir, an in-memory index has nothing to dump.
 func (vdb *ScaNNIndex) Dump() error {
+	if vdb.InMemory {
+		return nil
+	}
 	vdb.vdbCRwLock.RLock()
 	defer vdb.vdbCRwLock.RUnlock()
 
 	exeCode := C.vqindex_dump(vdb.vdbC)
 	if exeCode != 0 {
-		errMsg := fmt.Errorf("dump failed, exeCode: %v", exeCode)
+		errMsg := newRetCodeError("dump", exeCode)
 		log.Error().Err(errMsg)
 		return errMsg
 	} else {
@@ -0,0 +1,430 @@
+package hnsw
+
+import (
+	"container/heap"
+	"context"
+	"github.com/rs/zerolog/log"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	vqerrors "vqlite/errors"
+)
+
+// IndexType is the index type of collections indexed by a HNSW graph.
+const IndexType = "HNSW"
+
+// Defaults of the graph parameters, used when they are 0.
+const (
+	DefaultM              = 16
+	DefaultEfConstruction = 200
+	DefaultEfSearch       = 64
+)
+
+// Bounds of the graph parameters. A node needs at least 2 neighbors per layer, with 1 the levels
+// are not drawn from a finite distribution, and larger graphs take more memory without finding more.
+const (
+	MinM              = 2
+	MaxM              = 256
+	MaxEfConstruction = 10000
+)
+
+// Params are the parameters the graph is built with, they can not be changed once a vector is added.
+type Params struct {
+	M              int // neighbors of a node on the upper layers, twice as many on the base layer
+	EfConstruction int // candidates searched for the neighbors of a new node
+}
+
+// withDefaults sets the unset parameters to the defaults and keeps the others in their bounds.
+func (p Params) withDefaults() Params {
+	if p.M <= 0 {
+		p.M = DefaultM
+	}
+	if p.M < MinM {
+		p.M = MinM
+	}
+	if p.M > MaxM {
+		p.M = MaxM
+	}
+	if p.EfConstruction <= 0 {
+		p.EfConstruction = DefaultEfConstruction
+	}
+	if p.EfConstruction > MaxEfConstruction {
+		p.EfConstruction = MaxEfConstruction
+	}
+	return p
+}
+
+// Result is a vector found by a search, Score is the inner product with the query vector.
+type Result struct {
+	Vid   int64
+	Score float32
+}
+
+// Statistics of the index, Deleted vectors are still in the graph but never returned.
+type Statistics struct {
+	Size     int64
+	Deleted  int64
+	Dim      int
+	MaxLevel int
+	Params   Params
+}
+
+// Index is a HNSW graph over inner products, kept in memory and dumped to its work dir.
+//
+// Vectors are inserted into the graph as they are added, there is no training. Deleted vectors
+// are tombstoned: they still link the graph, but searches skip them.
+type Index struct {
+	Dim      int
+	WorkDir  string
+	InMemory bool
+
+	params    Params
+	levelMult float64
+	rng       *rand.Rand
+
+	lock      sync.RWMutex
+	vectors   []float32
+	vids      []int64
+	friends   [][][]uint32 // neighbors of every node on every layer it is on
+	deleted   []bool
+	deletes   int64
+	entry     int64 // entry node on the top layer, -1 while empty
+	maxLevel  int
+	persisted int // vectors in the dataset files, guarded by dumpLock
+	released  bool
+	dumpLock  sync.Mutex
+}
+
+// New returns the index in workDir, loading the dumped graph and dataset when there are any.
+// An in-memory index never touches workDir.
+func New(workDir string, dim int, params Params, inMemory bool) (*Index, error) {
+	if dim <= 0 {
+		return nil, vqerrors.InvalidArgument("hnsw dim %d must be greater than 0", dim)
+	}
+	params = params.withDefaults()
+	idx := &Index{
+		Dim:       dim,
+		WorkDir:   workDir,
+		InMemory:  inMemory,
+		params:    params,
+		levelMult: 1 / math.Log(float64(params.M)),
+		rng:       rand.New(rand.NewSource(rand.Int63())),
+		entry:     -1,
+	}
+	if !inMemory {
+		if err := idx.load(); err != nil {
+			return nil, err
+		}
+	}
+	log.Info().Msgf("created hnsw index success workDir:%s, Dim: %v, params: %+v, size: %d, inMemory: %v", workDir, dim, idx.params, len(idx.vids), inMemory)
+	return idx, nil
+}
+
+// Add inserts the vectors into the graph, searches wait until all of them are inserted.
+func (idx *Index) Add(vectors [][]float32, vids []int64) error {
+	if len(vectors) == 0 || len(vectors) != len(vids) {
+		return vqerrors.InvalidArgument("invalid length of vectors %d and vids %d", len(vectors), len(vids))
+	}
+	for _, vec := range vectors {
+		if len(vec) != idx.Dim {
+			return vqerrors.InvalidArgument("invalid length of vector %d, dim %d", len(vec), idx.Dim)
+		}
+	}
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	if idx.released {
+		return vqerrors.NotReady("index released")
+	}
+	for i, vec := range vectors {
+		idx.insert(vids[i], vec)
+	}
+	return nil
+}
+
+// Delete tombstones the vectors whose vid matches, it returns the number of vectors deleted.
+func (idx *Index) Delete(match func(vid int64) bool) int {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	deleted := 0
+	for i, vid := range idx.vids {
+		if !idx.deleted[i] && match(vid) {
+			idx.deleted[i] = true
+			deleted++
+		}
+	}
+	idx.deletes += int64(deleted)
+	return deleted
+}
+
+// Search returns the k nearest vectors of every query vector in xq, searching ef candidates on
+// the base layer, at least k, 0 uses DefaultEfSearch.
+func (idx *Index) Search(ctx context.Context, xq []float32, k int, ef int) ([][]Result, error) {
+	nq := len(xq) / idx.Dim
+	if nq < 1 || len(xq)%idx.Dim != 0 {
+		return nil, vqerrors.InvalidArgument("invalid xq size")
+	}
+	if ef <= 0 {
+		ef = DefaultEfSearch
+	}
+	if ef < k {
+		ef = k
+	}
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	if idx.released {
+		return nil, vqerrors.NotReady("index released")
+	}
+	res := make([][]Result, nq)
+	for i := 0; i < nq; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		res[i] = idx.search(xq[i*idx.Dim:(i+1)*idx.Dim], k, ef)
+	}
+	return res, nil
+}
+
+func (idx *Index) Statistics() Statistics {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	return Statistics{
+		Size:     int64(len(idx.vids)),
+		Deleted:  idx.deletes,
+		Dim:      idx.Dim,
+		MaxLevel: idx.maxLevel,
+		Params:   idx.params,
+	}
+}
+
+// Release frees the graph, later calls fail.
+func (idx *Index) Release() {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	idx.released = true
+	idx.vectors, idx.vids, idx.friends, idx.deleted = nil, nil, nil, nil
+	idx.entry = -1
+	log.Info().Msgf("release hnsw index %s", idx.WorkDir)
+}
+
+func (idx *Index) vector(node uint32) []float32 {
+	return idx.vectors[int(node)*idx.Dim : int(node+1)*idx.Dim]
+}
+
+func (idx *Index) score(q []float32, node uint32) float32 {
+	return innerProduct(q, idx.vector(node))
+}
+
+func (idx *Index) randomLevel() int {
+	return int(-math.Log(1-idx.rng.Float64()) * idx.levelMult)
+}
+
+func (idx *Index) maxFriends(level int) int {
+	if level == 0 {
+		return idx.params.M * 2
+	}
+	return idx.params.M
+}
+
+// insert adds a node for vec and links it to its nearest nodes on every layer up to its level.
+func (idx *Index) insert(vid int64, vec []float32) {
+	node := uint32(len(idx.vids))
+	level := idx.randomLevel()
+	idx.vectors = append(idx.vectors, vec...)
+	idx.vids = append(idx.vids, vid)
+	idx.friends = append(idx.friends, make([][]uint32, level+1))
+	idx.deleted = append(idx.deleted, false)
+	if idx.entry < 0 {
+		idx.entry = int64(node)
+		idx.maxLevel = level
+		return
+	}
+
+	q := idx.vector(node)
+	entry := uint32(idx.entry)
+	for l := idx.maxLevel; l > level; l-- {
+		entry = idx.greedy(q, entry, l)
+	}
+	top := level
+	if top > idx.maxLevel {
+		top = idx.maxLevel
+	}
+	for l := top; l >= 0; l-- {
+		candidates := idx.searchLayer(q, entry, idx.params.EfConstruction, l, false)
+		idx.friends[node][l] = idx.selectNeighbors(candidates, idx.params.M)
+		for _, friend := range idx.friends[node][l] {
+			friends := append(idx.friends[friend][l], node)
+			if len(friends) > idx.maxFriends(l) {
+				friends = idx.shrink(friend, friends, l)
+			}
+			idx.friends[friend][l] = friends
+		}
+		entry = candidates[0].node
+	}
+	if level > idx.maxLevel {
+		idx.maxLevel = level
+		idx.entry = int64(node)
+	}
+}
+
+// shrink keeps the best friends of node on layer l, diverse ones first.
+func (idx *Index) shrink(node uint32, friends []uint32, l int) []uint32 {
+	q := idx.vector(node)
+	candidates := make([]candidate, len(friends))
+	for i, friend := range friends {
+		candidates[i] = candidate{node: friend, score: idx.score(q, friend)}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	return idx.selectNeighbors(candidates, idx.maxFriends(l))
+}
+
+// selectNeighbors picks m of the candidates sorted by score, skipping a candidate closer to an already
+// picked one than to the query, so the neighbors point in different directions. The skipped ones fill
+// the rest when fewer than m were picked.
+func (idx *Index) selectNeighbors(candidates []candidate, m int) []uint32 {
+	selected := make([]uint32, 0, m)
+	skipped := make([]uint32, 0)
+	for _, c := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		diverse := true
+		vec := idx.vector(c.node)
+		for _, s := range selected {
+			if idx.score(vec, s) > c.score {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			selected = append(selected, c.node)
+		} else {
+			skipped = append(skipped, c.node)
+		}
+	}
+	for _, node := range skipped {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, node)
+	}
+	return selected
+}
+
+// greedy walks from entry to the node nearest to q on layer l.
+func (idx *Index) greedy(q []float32, entry uint32, l int) uint32 {
+	best := entry
+	bestScore := idx.score(q, entry)
+	for changed := true; changed; {
+		changed = false
+		for _, friend := range idx.friends[best][l] {
+			if score := idx.score(q, friend); score > bestScore {
+				best, bestScore = friend, score
+				changed = true
+			}
+		}
+	}
+	return best
+}
+
+// search returns the k nearest nodes of q which are not deleted.
+func (idx *Index) search(q []float32, k int, ef int) []Result {
+	if idx.entry < 0 {
+		return nil
+	}
+	entry := uint32(idx.entry)
+	for l := idx.maxLevel; l > 0; l-- {
+		entry = idx.greedy(q, entry, l)
+	}
+	candidates := idx.searchLayer(q, entry, ef, 0, true)
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+	results := make([]Result, len(candidates))
+	for i, c := range candidates {
+		results[i] = Result{Vid: idx.vids[c.node], Score: c.score}
+	}
+	return results
+}
+
+// searchLayer returns the ef nearest nodes of q on layer l found from entry, sorted by score desc.
+// With skipDeleted deleted nodes are walked through but not returned.
+func (idx *Index) searchLayer(q []float32, entry uint32, ef int, l int, skipDeleted bool) []candidate {
+	visited := map[uint32]struct{}{entry: {}}
+	first := candidate{node: entry, score: idx.score(q, entry)}
+	toVisit := &maxHeap{first}
+	found := &minHeap{}
+	if !skipDeleted || !idx.deleted[entry] {
+		heap.Push(found, first)
+	}
+	for toVisit.Len() > 0 {
+		c := heap.Pop(toVisit).(candidate)
+		if found.Len() >= ef && c.score < (*found)[0].score {
+			break
+		}
+		for _, friend := range idx.friends[c.node][l] {
+			if _, ok := visited[friend]; ok {
+				continue
+			}
+			visited[friend] = struct{}{}
+			score := idx.score(q, friend)
+			if found.Len() >= ef && score <= (*found)[0].score {
+				continue
+			}
+			heap.Push(toVisit, candidate{node: friend, score: score})
+			if skipDeleted && idx.deleted[friend] {
+				continue
+			}
+			heap.Push(found, candidate{node: friend, score: score})
+			if found.Len() > ef {
+				heap.Pop(found)
+			}
+		}
+	}
+	results := make([]candidate, found.Len())
+	for i := len(results) - 1; i >= 0; i-- {
+		results[i] = heap.Pop(found).(candidate)
+	}
+	return results
+}
+
+type candidate struct {
+	node  uint32
+	score float32
+}
+
+// maxHeap pops the candidate with the highest score first.
+type maxHeap []candidate
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[i].score > h[j].score }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// minHeap pops the candidate with the lowest score first.
+type minHeap []candidate
+
+func (h minHeap) Len() int            { return len(h) }
+func (h minHeap) Less(i, j int) bool  { return h[i].score < h[j].score }
+func (h minHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func innerProduct(a []float32, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,273 @@
+package hnsw
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+const testDim = 32
+
+// randomVectors returns n random unit vectors, so every vector is its own nearest neighbor.
+func randomVectors(rng *rand.Rand, n int) [][]float32 {
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vec := make([]float32, testDim)
+		var norm float64
+		for j := range vec {
+			vec[j] = float32(rng.NormFloat64())
+			norm += float64(vec[j] * vec[j])
+		}
+		for j := range vec {
+			vec[j] /= float32(math.Sqrt(norm))
+		}
+		vectors[i] = vec
+	}
+	return vectors
+}
+
+func sequentialVids(from int, n int) []int64 {
+	vids := make([]int64, n)
+	for i := range vids {
+		vids[i] = int64(from + i)
+	}
+	return vids
+}
+
+// bruteForce returns the vids of the k vectors with the highest inner product with q.
+func bruteForce(vectors [][]float32, q []float32, k int, skip func(vid int64) bool) map[int64]struct{} {
+	results := make([]Result, 0, len(vectors))
+	for i, vec := range vectors {
+		if skip != nil && skip(int64(i)) {
+			continue
+		}
+		results = append(results, Result{Vid: int64(i), Score: innerProduct(q, vec)})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	nearest := make(map[int64]struct{}, k)
+	for _, result := range results[:k] {
+		nearest[result.Vid] = struct{}{}
+	}
+	return nearest
+}
+
+func newTestIndex(t *testing.T, workDir string, params Params, inMemory bool) *Index {
+	t.Helper()
+	idx, err := New(workDir, testDim, params, inMemory)
+	if err != nil {
+		t.Fatalf("new index: %v", err)
+	}
+	t.Cleanup(idx.Release)
+	return idx
+}
+
+func search(t *testing.T, idx *Index, q []float32, k int, ef int) []Result {
+	t.Helper()
+	results, err := idx.Search(context.Background(), q, k, ef)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	return results[0]
+}
+
+func TestSearchRecall(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	vectors := randomVectors(rng, 3000)
+	idx := newTestIndex(t, t.TempDir(), Params{}, true)
+	if err := idx.Add(vectors, sequentialVids(0, len(vectors))); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	const k = 10
+	queries := randomVectors(rng, 100)
+	found := 0
+	for _, q := range queries {
+		nearest := bruteForce(vectors, q, k, nil)
+		results := search(t, idx, q, k, 100)
+		if len(results) != k {
+			t.Fatalf("search returned %d results, want %d", len(results), k)
+		}
+		for _, result := range results {
+			if _, ok := nearest[result.Vid]; ok {
+				found++
+			}
+		}
+	}
+	if recall := float64(found) / float64(k*len(queries)); recall < 0.95 {
+		t.Errorf("recall@%d is %.3f, want at least 0.95", k, recall)
+	}
+}
+
+func TestDeleteSkipsTombstones(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	vectors := randomVectors(rng, 1000)
+	idx := newTestIndex(t, t.TempDir(), Params{}, true)
+	if err := idx.Add(vectors, sequentialVids(0, len(vectors))); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	even := func(vid int64) bool { return vid%2 == 0 }
+	if deleted := idx.Delete(even); deleted != 500 {
+		t.Fatalf("deleted %d vectors, want 500", deleted)
+	}
+	if deleted := idx.Delete(even); deleted != 0 {
+		t.Errorf("deleted %d vectors again, want 0", deleted)
+	}
+	if stat := idx.Statistics(); stat.Size != 1000 || stat.Deleted != 500 {
+		t.Errorf("statistics size %d deleted %d, want 1000 and 500", stat.Size, stat.Deleted)
+	}
+
+	const k = 10
+	for i, q := range randomVectors(rng, 50) {
+		results := search(t, idx, q, k, 64)
+		if len(results) != k {
+			t.Fatalf("query %d returned %d results, want %d", i, len(results), k)
+		}
+		for _, result := range results {
+			if even(result.Vid) {
+				t.Fatalf("query %d returned deleted vid %d", i, result.Vid)
+			}
+		}
+	}
+	// a deleted vector is not found even by itself
+	if results := search(t, idx, vectors[0], 1, 64); len(results) == 1 && results[0].Vid == 0 {
+		t.Errorf("deleted vid 0 found by its own vector")
+	}
+}
+
+func TestDumpAndLoad(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	vectors := randomVectors(rng, 800)
+	workDir := t.TempDir()
+	idx := newTestIndex(t, workDir, Params{M: 8, EfConstruction: 100}, false)
+	if err := idx.Add(vectors[:500], sequentialVids(0, 500)); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	idx.Delete(func(vid int64) bool { return vid == 7 })
+	if err := idx.Dump(); err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	// the second dump only appends the new vectors
+	if err := idx.Add(vectors[500:700], sequentialVids(500, 200)); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := idx.Dump(); err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+
+	loaded := newTestIndex(t, workDir, Params{}, false)
+	stat := loaded.Statistics()
+	if stat.Size != 700 || stat.Deleted != 1 || stat.Params != (Params{M: 8, EfConstruction: 100}) {
+		t.Fatalf("loaded statistics %+v, want 700 vectors, 1 deleted and the params of the dump", stat)
+	}
+	for _, q := range randomVectors(rng, 20) {
+		want := search(t, idx, q, 10, 64)
+		got := search(t, loaded, q, 10, 64)
+		if len(got) != len(want) {
+			t.Fatalf("loaded index returned %d results, want %d", len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("loaded index returned %+v, want %+v", got, want)
+			}
+		}
+	}
+	if results := search(t, loaded, vectors[7], 1, 64); len(results) == 1 && results[0].Vid == 7 {
+		t.Errorf("deleted vid 7 found after loading")
+	}
+}
+
+func TestLoadPartlyWrittenDataset(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	vectors := randomVectors(rng, 400)
+	workDir := t.TempDir()
+	idx := newTestIndex(t, workDir, Params{}, false)
+	if err := idx.Add(vectors[:300], sequentialVids(0, 300)); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := idx.Dump(); err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	graphFilename := filepath.Join(workDir, graphFileName)
+	oldGraph, err := os.ReadFile(graphFilename)
+	if err != nil {
+		t.Fatalf("read graph: %v", err)
+	}
+	if err := idx.Add(vectors[300:], sequentialVids(300, 100)); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := idx.Dump(); err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+
+	// a dump which wrote the dataset and half of the next vector, but not the graph
+	if err := os.WriteFile(graphFilename, oldGraph, 0644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	datasetsFilename := filepath.Join(workDir, datasetsFileName)
+	datasets, err := os.OpenFile(datasetsFilename, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("open dataset: %v", err)
+	}
+	if _, err := datasets.Write(make([]byte, testDim*2)); err != nil {
+		t.Fatalf("write dataset: %v", err)
+	}
+	datasets.Close()
+
+	loaded := newTestIndex(t, workDir, Params{}, false)
+	if size := loaded.Statistics().Size; size != 400 {
+		t.Fatalf("loaded %d vectors, want 400", size)
+	}
+	// the vectors missing in the graph are inserted again
+	for vid := 300; vid < 400; vid++ {
+		if results := search(t, loaded, vectors[vid], 1, 64); len(results) != 1 || results[0].Vid != int64(vid) {
+			t.Fatalf("vid %d not found by its own vector, got %+v", vid, results)
+		}
+	}
+
+	// the next dump cuts off the partly written vector
+	if err := loaded.Add(randomVectors(rng, 1), []int64{400}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := loaded.Dump(); err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	info, err := os.Stat(datasetsFilename)
+	if err != nil {
+		t.Fatalf("stat dataset: %v", err)
+	}
+	if want := int64(401 * testDim * 4); info.Size() != want {
+		t.Errorf("dataset has %d bytes, want %d", info.Size(), want)
+	}
+}
+
+func TestParamsBounds(t *testing.T) {
+	cases := []struct {
+		params Params
+		want   Params
+	}{
+		{Params{}, Params{M: DefaultM, EfConstruction: DefaultEfConstruction}},
+		{Params{M: 1, EfConstruction: 1}, Params{M: MinM, EfConstruction: 1}},
+		{Params{M: MaxM + 1, EfConstruction: MaxEfConstruction + 1}, Params{M: MaxM, EfConstruction: MaxEfConstruction}},
+		{Params{M: 32, EfConstruction: 400}, Params{M: 32, EfConstruction: 400}},
+	}
+	for _, c := range cases {
+		if got := c.params.withDefaults(); got != c.want {
+			t.Errorf("%+v with defaults is %+v, want %+v", c.params, got, c.want)
+		}
+	}
+
+	// the smallest graph still builds and searches
+	rng := rand.New(rand.NewSource(5))
+	vectors := randomVectors(rng, 200)
+	idx := newTestIndex(t, t.TempDir(), Params{M: 1, EfConstruction: 1}, true)
+	if err := idx.Add(vectors, sequentialVids(0, len(vectors))); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if results := search(t, idx, vectors[0], 5, 0); len(results) != 5 {
+		t.Errorf("search returned %d results, want 5", len(results))
+	}
+}
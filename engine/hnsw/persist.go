@@ -0,0 +1,209 @@
+package hnsw
+
+import (
+	"bufio"
+	"encoding/binary"
+	"github.com/rs/zerolog/log"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	vqerrors "vqlite/errors"
+	"vqlite/utils"
+)
+
+// The dataset files have the layout of the engine, so the dataset of a HNSW segment is read the same way:
+// the vectors back to back as little endian float32 and the vids as little endian int64.
+const (
+	datasetsFileName = "datasets.vql"
+	vidsFileName     = "vids.vql"
+	graphFileName    = "hnsw.gob"
+)
+
+// graph is the dumped graph of the first Size vectors of the dataset.
+type graph struct {
+	Params   Params
+	Size     int
+	Entry    int64
+	MaxLevel int
+	Friends  [][][]uint32
+	Deleted  []bool
+}
+
+// Dump appends the vectors added since the last dump to the dataset files and dumps the graph.
+// An in-memory index has nothing to dump.
+func (idx *Index) Dump() error {
+	if idx.InMemory {
+		return nil
+	}
+	// dumps append to the dataset files one at a time, adds wait for the dump
+	idx.dumpLock.Lock()
+	defer idx.dumpLock.Unlock()
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	if idx.released {
+		return vqerrors.NotReady("index released")
+	}
+	if !utils.IsDir(idx.WorkDir) {
+		utils.CreateDirPath(idx.WorkDir)
+	}
+	size := len(idx.vids)
+	if err := idx.appendDataset(size); err != nil {
+		log.Error().Err(err).Msgf("dump hnsw dataset of %s error", idx.WorkDir)
+		return err
+	}
+	// the dataset is written first, vectors missing in the graph are inserted again when loading
+	g := graph{
+		Params:   idx.params,
+		Size:     size,
+		Entry:    idx.entry,
+		MaxLevel: idx.maxLevel,
+		Friends:  idx.friends,
+		Deleted:  idx.deleted,
+	}
+	if err := utils.Dump(g, filepath.Join(idx.WorkDir, graphFileName)); err != nil {
+		log.Error().Err(err).Msgf("dump hnsw graph of %s error", idx.WorkDir)
+		return err
+	}
+	log.Info().Msgf("dump hnsw index success, workDir: %s, size: %d", idx.WorkDir, size)
+	return nil
+}
+
+// appendDataset writes the vectors from persisted to size to the dataset files, cutting off what
+// a failed dump left behind the persisted vectors first.
+func (idx *Index) appendDataset(size int) error {
+	if size == idx.persisted {
+		return nil
+	}
+	vectorBytes := int64(idx.Dim) * 4
+	vectors := make([]byte, int64(size-idx.persisted)*vectorBytes)
+	vids := make([]byte, (size-idx.persisted)*8)
+	for i, x := range idx.vectors[idx.persisted*idx.Dim : size*idx.Dim] {
+		binary.LittleEndian.PutUint32(vectors[i*4:], math.Float32bits(x))
+	}
+	for i, vid := range idx.vids[idx.persisted:size] {
+		binary.LittleEndian.PutUint64(vids[i*8:], uint64(vid))
+	}
+	if err := appendFile(filepath.Join(idx.WorkDir, datasetsFileName), int64(idx.persisted)*vectorBytes, vectors); err != nil {
+		return err
+	}
+	if err := appendFile(filepath.Join(idx.WorkDir, vidsFileName), int64(idx.persisted)*8, vids); err != nil {
+		return err
+	}
+	idx.persisted = size
+	return nil
+}
+
+func appendFile(filename string, offset int64, data []byte) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.WriteAt(data, offset); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// load reads the dataset and graph in the work dir, the vectors of the dataset which are not in
+// the graph are inserted, the whole graph is built again when it does not match the dataset.
+func (idx *Index) load() error {
+	vectors, vids, err := idx.readDataset()
+	if err != nil {
+		return err
+	}
+	size := len(vids)
+	g := graph{}
+	graphFilename := filepath.Join(idx.WorkDir, graphFileName)
+	if utils.Exists(graphFilename) {
+		if err := utils.Load(&g, graphFilename); err != nil {
+			log.Error().Err(err).Msgf("load hnsw graph of %s error, building it again", idx.WorkDir)
+			g = graph{}
+		}
+	}
+	if g.Size > size || len(g.Friends) != g.Size || len(g.Deleted) != g.Size {
+		log.Warn().Msgf("hnsw graph of %s has %d of %d vectors, building it again", idx.WorkDir, g.Size, size)
+		g = graph{}
+	}
+	if g.Size > 0 {
+		idx.params = g.Params.withDefaults()
+		idx.levelMult = 1 / math.Log(float64(idx.params.M))
+		idx.vectors = vectors[:g.Size*idx.Dim]
+		idx.vids = vids[:g.Size]
+		idx.friends = g.Friends
+		idx.deleted = g.Deleted
+		idx.entry = g.Entry
+		idx.maxLevel = g.MaxLevel
+		for _, deleted := range idx.deleted {
+			if deleted {
+				idx.deletes++
+			}
+		}
+	}
+	for i := g.Size; i < size; i++ {
+		idx.insert(vids[i], vectors[i*idx.Dim:(i+1)*idx.Dim])
+	}
+	idx.persisted = size
+	return nil
+}
+
+// readDataset reads the vectors and vids of the dataset files, a partly written vector at the end is dropped.
+func (idx *Index) readDataset() ([]float32, []int64, error) {
+	datasetsFilename := filepath.Join(idx.WorkDir, datasetsFileName)
+	vidsFilename := filepath.Join(idx.WorkDir, vidsFileName)
+	if !utils.Exists(datasetsFilename) || !utils.Exists(vidsFilename) {
+		return nil, nil, nil
+	}
+	datasets, err := os.Open(datasetsFilename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer datasets.Close()
+	vidsFile, err := os.Open(vidsFilename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer vidsFile.Close()
+	datasetsInfo, err := datasets.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	vidsInfo, err := vidsFile.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	vectorBytes := int64(idx.Dim) * 4
+	size := datasetsInfo.Size() / vectorBytes
+	if vidsSize := vidsInfo.Size() / 8; vidsSize < size {
+		size = vidsSize
+	}
+
+	vectors := make([]float32, size*int64(idx.Dim))
+	reader := bufio.NewReaderSize(datasets, 1<<20)
+	buf := make([]byte, 4)
+	for i := range vectors {
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, nil, err
+		}
+		vectors[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf))
+	}
+	vids := make([]int64, size)
+	reader = bufio.NewReaderSize(vidsFile, 1<<20)
+	buf = make([]byte, 8)
+	for i := range vids {
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, nil, err
+		}
+		vids[i] = int64(binary.LittleEndian.Uint64(buf))
+	}
+	return vectors, vids, nil
+}
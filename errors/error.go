@@ -1 +1,157 @@
 package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code classifies an error, every code maps to one HTTP status.
+type Code string
+
+const (
+	CodeNotFound          Code = "NOT_FOUND"
+	CodeAlreadyExists     Code = "ALREADY_EXISTS"
+	CodeInvalidArgument   Code = "INVALID_ARGUMENT"
+	CodeNotReady          Code = "NOT_READY"
+	CodeResourceExhausted Code = "RESOURCE_EXHAUSTED"
+	CodeInternal          Code = "INTERNAL"
+	CodeUnauthenticated   Code = "UNAUTHENTICATED"
+	CodePermissionDenied  Code = "PERMISSION_DENIED"
+	CodeRateLimited       Code = "RATE_LIMITED"
+	CodeTooLarge          Code = "TOO_LARGE"
+	CodeDeadlineExceeded  Code = "DEADLINE_EXCEEDED"
+	CodeCanceled          Code = "CANCELED"
+)
+
+// codeHTTPStatus maps the codes to HTTP statuses. The engine running out of memory is a 500 rather than
+// a retryable 503, since a POST may have been applied in part, e.g. documents added before the index failed.
+var codeHTTPStatus = map[Code]int{
+	CodeNotFound:          http.StatusNotFound,
+	CodeAlreadyExists:     http.StatusConflict,
+	CodeInvalidArgument:   http.StatusBadRequest,
+	CodeNotReady:          http.StatusServiceUnavailable,
+	CodeResourceExhausted: http.StatusInternalServerError,
+	CodeInternal:          http.StatusInternalServerError,
+	CodeUnauthenticated:   http.StatusUnauthorized,
+	CodePermissionDenied:  http.StatusForbidden,
+	CodeRateLimited:       http.StatusTooManyRequests,
+	CodeTooLarge:          http.StatusRequestEntityTooLarge,
+	CodeDeadlineExceeded:  http.StatusGatewayTimeout,
+	// the client closed the request, like nginx
+	CodeCanceled: 499,
+}
+
+// Coder is implemented by errors which carry a Code.
+type Coder interface {
+	ErrorCode() Code
+}
+
+// Error is an error with a Code, EngineCode keeps the ret code of the index engine
+// for errors returned by it, e.g. RET_CODE_MEMORYERR.
+type Error struct {
+	Code       Code
+	Message    string
+	EngineCode string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s", e.Message, e.Err.Error())
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) ErrorCode() Code {
+	return e.Code
+}
+
+func New(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap returns an error with code and message, err is kept as the cause.
+func Wrap(code Code, err error, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+func NotFound(format string, args ...any) *Error {
+	return New(CodeNotFound, format, args...)
+}
+
+func AlreadyExists(format string, args ...any) *Error {
+	return New(CodeAlreadyExists, format, args...)
+}
+
+func InvalidArgument(format string, args ...any) *Error {
+	return New(CodeInvalidArgument, format, args...)
+}
+
+func NotReady(format string, args ...any) *Error {
+	return New(CodeNotReady, format, args...)
+}
+
+func ResourceExhausted(format string, args ...any) *Error {
+	return New(CodeResourceExhausted, format, args...)
+}
+
+func Internal(format string, args ...any) *Error {
+	return New(CodeInternal, format, args...)
+}
+
+func Unauthenticated(format string, args ...any) *Error {
+	return New(CodeUnauthenticated, format, args...)
+}
+
+func PermissionDenied(format string, args ...any) *Error {
+	return New(CodePermissionDenied, format, args...)
+}
+
+func TooLarge(format string, args ...any) *Error {
+	return New(CodeTooLarge, format, args...)
+}
+
+// CodeOf returns the code of the first error in the chain of err which carries one,
+// errors without a code are internal.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	var coder Coder
+	if errors.As(err, &coder) {
+		return coder.ErrorCode()
+	}
+	return CodeInternal
+}
+
+// EngineCodeOf returns the ret code of the index engine in the chain of err, if any.
+func EngineCodeOf(err error) string {
+	var e *Error
+	for err != nil {
+		if !errors.As(err, &e) {
+			return ""
+		}
+		if e.EngineCode != "" {
+			return e.EngineCode
+		}
+		err = e.Err
+	}
+	return ""
+}
+
+func IsCode(err error, code Code) bool {
+	return CodeOf(err) == code
+}
+
+// HTTPStatus returns the HTTP status for the code of err.
+func HTTPStatus(err error) int {
+	if status, ok := codeHTTPStatus[CodeOf(err)]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
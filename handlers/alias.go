@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"vqlite/auth"
+	"vqlite/core"
+	vqerrors "vqlite/errors"
+)
+
+func SetAlias(c *gin.Context) {
+	alias := c.Param("name")
+	var aliasReq core.SetAliasRequest
+
+	if err := c.ShouldBindJSON(&aliasReq); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	if err := core.SetAlias(alias, aliasReq.Collection); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+	})
+}
+
+func GetAlias(c *gin.Context) {
+	alias := c.Param("name")
+
+	collectionName, err := core.GetAlias(alias)
+	if err == nil && !auth.FromContext(c.Request.Context()).Allows(collectionName) {
+		err = vqerrors.NotFound("alias [%s] not exists", alias)
+	}
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data": gin.H{
+			"alias":      alias,
+			"collection": collectionName,
+		},
+	})
+}
+
+func ListAliases(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data":   core.ListAliases(auth.FromContext(c.Request.Context()).Allows),
+	})
+}
+
+func DeleteAlias(c *gin.Context) {
+	alias := c.Param("name")
+
+	if err := core.DeleteAlias(alias); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+	})
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
+	"vqlite/auth"
 	"vqlite/core"
 )
 
@@ -15,7 +17,7 @@ func GetHealth(c *gin.Context) {
 }
 
 func VQLiteStatistics(c *gin.Context) {
-	vqliteStatistics := core.Statistics()
+	vqliteStatistics := core.Statistics(auth.FromContext(c.Request.Context()).Allows)
 	c.JSON(http.StatusOK, gin.H{
 		"status": "ok",
 		"data":   vqliteStatistics,
@@ -26,17 +28,17 @@ func CreateCollection(c *gin.Context) {
 
 	var newCol core.CreateCollectionRequest
 
-	if err := c.BindJSON(&newCol); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&newCol); err != nil {
+		abortWithBindError(c, err)
 		return
 	}
 	collectionName := c.Param("target")
 	if collectionName != "" {
 		newCol.Name = collectionName
 	}
-	col, err := core.CreateCollection(newCol.Name, newCol.Dim)
+	col, err := core.CreateCollection(&newCol)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 
@@ -47,59 +49,221 @@ func CreateCollection(c *gin.Context) {
 
 }
 
+// GetCollection returns the collection statistics with its load state, without loading it.
+func GetCollection(c *gin.Context) {
+	collectionName := c.Param("target")
+
+	col, err := core.GetCollection(collectionName)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data":   col.Statistics(),
+	})
+}
+
 func DropCollection(c *gin.Context) {
 	collectionName := c.Param("target")
-	core.DropCollection(collectionName)
+	if err := core.DropCollection(collectionName); err != nil {
+		abortWithError(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status": "ok",
 	})
 }
 
-func SearchCollection(c *gin.Context) {
+func RenameCollection(c *gin.Context) {
 	collectionName := c.Param("target")
-	var searchReq core.SearchRequest
-	if err := c.BindJSON(&searchReq); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var renameReq core.RenameCollectionRequest
+
+	if err := c.ShouldBindJSON(&renameReq); err != nil {
+		abortWithBindError(c, err)
 		return
 	}
 
-	result, err := core.SearchCollection(collectionName, searchReq.Vectors, searchReq.Opt)
+	col, err := core.RenameCollection(collectionName, renameReq.Name)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status": "ok",
-		"data":   result,
+		"data":   col.Statistics(),
+	})
+}
+
+func CloneCollection(c *gin.Context) {
+	collectionName := c.Param("target")
+	var cloneReq core.CloneCollectionRequest
+
+	if err := c.ShouldBindJSON(&cloneReq); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	col, err := core.CloneCollection(collectionName, cloneReq.Name)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data":   col.Statistics(),
 	})
+}
+
+func SearchCollection(c *gin.Context) {
+	collectionName := c.Param("target")
+	var searchReq core.SearchRequest
+	if err := c.ShouldBindJSON(&searchReq); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	if debug, _ := strconv.ParseBool(c.Query("debug")); debug {
+		searchReq.Opt.Debug = true
+	}
+
+	// the search is abandoned when the client disconnects
+	result, err := core.SearchCollection(c.Request.Context(), collectionName, searchReq.Vectors, searchReq.Opt)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	body := gin.H{
+		"status":  "ok",
+		"data":    result.Results,
+		"partial": result.Partial,
+	}
+	if result.Partial {
+		body["failed_segments"] = result.FailedSegments
+	}
+	if result.Debug != nil {
+		body["debug"] = result.Debug
+	}
+	c.JSON(http.StatusOK, body)
 
 }
 func TrainCollection(c *gin.Context) {
 	collectionName := c.Param("target")
 	var trainReq core.TrainRequest
 
-	if err := c.BindJSON(&trainReq); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&trainReq); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	if err := core.TrainCollection(collectionName, trainReq.Threads, trainReq.IgnoreCheck, trainReq.TrainOpt); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+	})
+
+}
+
+// SetTrainOpt stores the parameters used by trainings of the collection which leave them unset.
+func SetTrainOpt(c *gin.Context) {
+	collectionName := c.Param("target")
+	var opt core.TrainOpt
+
+	if err := c.ShouldBindJSON(&opt); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	col, err := core.SetTrainOpt(collectionName, opt)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data":   col.Statistics(),
+	})
+}
+
+// EvalCollection reports the recall and latency of the search for a grid of nprobe and reorder.
+func EvalCollection(c *gin.Context) {
+	collectionName := c.Param("target")
+	var evalReq core.EvalRequest
+
+	if err := c.ShouldBindJSON(&evalReq); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	result, err := core.EvalCollection(c.Request.Context(), collectionName, &evalReq)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data":   result,
+	})
+}
+
+// SetSearchOpt stores the options used by searches of the collection which leave them unset.
+func SetSearchOpt(c *gin.Context) {
+	collectionName := c.Param("target")
+	var opt core.QueryOpt
+
+	if err := c.ShouldBindJSON(&opt); err != nil {
+		abortWithBindError(c, err)
 		return
 	}
 
-	if err := core.TrainCollection(collectionName, trainReq.Threads, trainReq.IgnoreCheck); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	col, err := core.SetSearchOpt(collectionName, opt)
+	if err != nil {
+		abortWithError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status": "ok",
+		"data":   col.Statistics(),
 	})
+}
+
+// TuneCollection picks and stores the nprobe and reorder with the least latency for a target recall.
+func TuneCollection(c *gin.Context) {
+	collectionName := c.Param("target")
+	var tuneReq core.TuneRequest
+
+	if err := c.ShouldBindJSON(&tuneReq); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	result, err := core.TuneCollection(c.Request.Context(), collectionName, &tuneReq)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
 
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data":   result,
+	})
 }
 
 func DumpCollection(c *gin.Context) {
 	collectionName := c.Param("target")
 
 	if err := core.DumpCollection(collectionName); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -111,7 +275,7 @@ func DumpCollectionMetadata(c *gin.Context) {
 	collectionName := c.Param("target")
 
 	if err := core.DumpCollectionMetadata(collectionName); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -122,7 +286,7 @@ func DumpCollectionIndex(c *gin.Context) {
 	collectionName := c.Param("target")
 
 	if err := core.DumpCollectionIndex(collectionName); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -134,7 +298,7 @@ func LoadCollection(c *gin.Context) {
 	collectionName := c.Param("target")
 
 	if err := core.LoadCollection(collectionName); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -142,3 +306,15 @@ func LoadCollection(c *gin.Context) {
 	})
 
 }
+
+func UnloadCollection(c *gin.Context) {
+	collectionName := c.Param("target")
+
+	if err := core.UnloadCollection(collectionName); err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+	})
+}
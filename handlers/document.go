@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"vqlite/core"
+	vqerrors "vqlite/errors"
 )
 
 func AddDocument(c *gin.Context) {
@@ -11,13 +12,13 @@ func AddDocument(c *gin.Context) {
 
 	var doc core.AddDocumentRequest
 
-	if err := c.BindJSON(&doc); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		abortWithBindError(c, err)
 		return
 	}
 	err := core.AddDocument(collectionName, &doc)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -30,17 +31,17 @@ func BatchAddDocuments(c *gin.Context) {
 	collectionName := c.Param("target")
 
 	var docs core.BatchAddDocumentsRequest
-	if err := c.BindJSON(&docs); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&docs); err != nil {
+		abortWithBindError(c, err)
 		return
 	}
 	if len(docs.Documents) == 0 {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Documents is empty"})
+		abortWithError(c, vqerrors.InvalidArgument("documents is empty"))
 		return
 	}
 	err := core.BatchAddDocuments(collectionName, &docs)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -51,15 +52,15 @@ func BatchAddDocuments(c *gin.Context) {
 func DeleteDocument(c *gin.Context) {
 	collectionName := c.Param("target")
 	var doc core.DeleteDocumentRequest
-	if err := c.BindJSON(&doc); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		abortWithBindError(c, err)
 		return
 	}
 
 	vqid := doc.Vqid
 	deletedCount, err := core.DeleteDocument(collectionName, vqid)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 
@@ -72,15 +73,15 @@ func DeleteDocument(c *gin.Context) {
 func UpdateDocumentMetadata(c *gin.Context) {
 	collectionName := c.Param("target")
 	var doc core.UpdateDocumentMetadataRequest
-	if err := c.BindJSON(&doc); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		abortWithBindError(c, err)
 		return
 	}
 
 	updatedCount, err := core.UpdateDocumentMetadata(collectionName, &doc)
 
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 
@@ -109,7 +110,7 @@ func GetDocumentMetadata(c *gin.Context) {
 	metadataList, err := core.GetDocumentMetadata(collectionName, vqid, checkDuplicate)
 
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	vqerrors "vqlite/errors"
+)
+
+// abortWithError aborts the request with err, the response is written by middlewares.ErrorResponse
+// with the HTTP status matching the error code.
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// abortWithBindError aborts the request with an invalid argument error for a request which can not be bound,
+// bodies above the size limit keep their too large error.
+func abortWithBindError(c *gin.Context, err error) {
+	if vqerrors.IsCode(err, vqerrors.CodeTooLarge) {
+		abortWithError(c, err)
+		return
+	}
+	abortWithError(c, vqerrors.Wrap(vqerrors.CodeInvalidArgument, err, "invalid request"))
+}
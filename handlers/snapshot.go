@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net/http"
+	"time"
+	"vqlite/core"
+)
+
+func SnapshotCollection(c *gin.Context) {
+	collectionName := c.Param("target")
+
+	archive, err := core.SnapshotCollection(collectionName)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	defer func() {
+		if err := archive.Close(); err != nil {
+			log.Warn().Err(err).Msgf("remove snapshot archive of collection [%s] error", collectionName)
+		}
+	}()
+
+	// the length and checksum let clients detect an archive cut off while it is sent
+	filename := fmt.Sprintf("%s_%s.tar", collectionName, time.Now().Format("20060102150405"))
+	c.DataFromReader(http.StatusOK, archive.Size, "application/x-tar", archive, map[string]string{
+		"Content-Disposition":     fmt.Sprintf("attachment; filename=%q", filename),
+		core.SnapshotSha256Header: archive.Sha256,
+	})
+}
+
+func RestoreCollection(c *gin.Context) {
+	collectionName := c.Param("target")
+
+	manifest, err := core.RestoreCollection(collectionName, c.Request.Body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data":   manifest,
+	})
+}
+
+func CreateSnapshot(c *gin.Context) {
+	collectionName := c.Param("target")
+
+	var snapshotReq core.CreateSnapshotRequest
+	// the body is optional, the snapshot name defaults to current time
+	if err := c.ShouldBindJSON(&snapshotReq); err != nil && err != io.EOF {
+		abortWithBindError(c, err)
+		return
+	}
+
+	manifest, err := core.CreateCollectionSnapshot(collectionName, snapshotReq.Name)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data":   manifest,
+	})
+}
+
+func ListSnapshots(c *gin.Context) {
+	collectionName := c.Param("target")
+
+	snapshots, err := core.ListCollectionSnapshots(collectionName)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data":   snapshots,
+	})
+}
+
+func RestoreSnapshot(c *gin.Context) {
+	collectionName := c.Param("target")
+	snapshotName := c.Param("snapshot")
+
+	manifest, err := core.RestoreCollectionSnapshot(collectionName, snapshotName)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data":   manifest,
+	})
+}
+
+func DeleteSnapshot(c *gin.Context) {
+	collectionName := c.Param("target")
+	snapshotName := c.Param("snapshot")
+
+	if err := core.DeleteCollectionSnapshot(collectionName, snapshotName); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+	})
+}
@@ -0,0 +1,19 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"vqlite/core"
+)
+
+// ResolveAlias replaces the :target param with the collection name when it is an alias,
+// so every handler taking :target works with aliases.
+func ResolveAlias() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for i, param := range c.Params {
+			if param.Key == "target" {
+				c.Params[i].Value = core.VqliteCollectionList.Resolve(param.Value)
+			}
+		}
+		c.Next()
+	}
+}
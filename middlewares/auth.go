@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"vqlite/auth"
+)
+
+const authKey = "auth.key"
+
+// Authenticate checks the API key of the request and keeps it for RequireRole,
+// it does nothing when auth is disabled.
+func Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.Enabled() {
+			c.Next()
+			return
+		}
+		key, err := auth.Authenticate(auth.Token(c.GetHeader("Authorization"), c.GetHeader("X-API-Key")))
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Set(authKey, key)
+		// the handlers filter statistics and aliases by the key
+		c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), key))
+		c.Next()
+	}
+}
+
+// RequireRole allows keys with role, scoped keys only for the collection of :target.
+// It must come after Authenticate and ResolveAlias.
+func RequireRole(role auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.Enabled() {
+			c.Next()
+			return
+		}
+		key := c.MustGet(authKey).(*auth.Key)
+		if err := key.Authorize(role, c.Param("target")); err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// AuthenticateDebug guards /metrics and /debug/pprof with the debug keys.
+func AuthenticateDebug() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.Enabled() {
+			c.Next()
+			return
+		}
+		if err := auth.AuthenticateDebug(auth.Token(c.GetHeader("Authorization"), c.GetHeader("X-API-Key"))); err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"vqlite/core"
+	vqerrors "vqlite/errors"
+	"vqlite/ratelimit"
+)
+
+// ErrorResponse writes the last error added by the handler with c.Error, the HTTP status
+// follows the error code and the body is the same for every error:
+//
+//	{"status": "error", "code": "NOT_FOUND", "error": "collection [foo] not exists"}
+//
+// engine_code is added for errors of the index engine, Retry-After with the collection
+// state for collections which are not loaded yet, and Retry-After for rate limited requests.
+func ErrorResponse() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+
+		body := gin.H{
+			"status": "error",
+			"code":   vqerrors.CodeOf(err),
+			"error":  err.Error(),
+		}
+		if engineCode := vqerrors.EngineCodeOf(err); engineCode != "" {
+			body["engine_code"] = engineCode
+		}
+		var notReadyErr *core.CollectionNotReadyError
+		if errors.As(err, &notReadyErr) {
+			c.Header("Retry-After", strconv.Itoa(notReadyErr.RetryAfter))
+			body["state"] = notReadyErr.State
+		}
+		var rateLimitedErr *ratelimit.RateLimitedError
+		if errors.As(err, &rateLimitedErr) {
+			c.Header("Retry-After", strconv.Itoa(rateLimitedErr.RetryAfter))
+		}
+		c.JSON(vqerrors.HTTPStatus(err), body)
+	}
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"io"
+	"vqlite/config"
+	vqerrors "vqlite/errors"
+)
+
+// LimitBody rejects request bodies larger than maxBodySize, except for the routes in exclude,
+// e.g. the upload of snapshot archives.
+func LimitBody(exclude ...string) gin.HandlerFunc {
+	excluded := make(map[string]struct{}, len(exclude))
+	for _, path := range exclude {
+		excluded[path] = struct{}{}
+	}
+	return func(c *gin.Context) {
+		if _, ok := excluded[c.FullPath()]; ok || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		maxBodySize := config.GlobalConfig.LimitConfig.MaxBodySize * 1024 * 1024
+		if c.Request.ContentLength > maxBodySize {
+			_ = c.Error(tooLargeBodyError(maxBodySize))
+			c.Abort()
+			return
+		}
+		c.Request.Body = &limitedBody{ReadCloser: c.Request.Body, remaining: maxBodySize, limit: maxBodySize}
+		c.Next()
+	}
+}
+
+func tooLargeBodyError(maxBodySize int64) error {
+	return vqerrors.TooLarge("request body is larger than %d MB", maxBodySize/1024/1024)
+}
+
+// limitedBody returns a too large error after reading more than remaining bytes,
+// for bodies without a content length.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	limit     int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, tooLargeBodyError(b.limit)
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n, tooLargeBodyError(b.limit)
+	}
+	return n, err
+}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"vqlite/auth"
+	"vqlite/ratelimit"
+)
+
+// RateLimit limits the requests of the API key, or the client IP without auth, for the routes of class.
+// It must come after Authenticate.
+func RateLimit(class ratelimit.Class) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := ratelimit.Allow(class, clientOf(c)); err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// AdmitSearch limits the number of searches run at once, searches above it wait for a slot up to the queue timeout.
+func AdmitSearch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		release, err := ratelimit.AdmitSearch(c.Request.Context())
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
+
+func clientOf(c *gin.Context) string {
+	if key, ok := c.Get(authKey); ok {
+		return "key:" + key.(*auth.Key).Name
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,1233 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.23.4
+// source: vqlite.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	VQLite_Ping_FullMethodName                   = "/vqlite.VQLite/Ping"
+	VQLite_Statistics_FullMethodName             = "/vqlite.VQLite/Statistics"
+	VQLite_GetCollection_FullMethodName          = "/vqlite.VQLite/GetCollection"
+	VQLite_CreateCollection_FullMethodName       = "/vqlite.VQLite/CreateCollection"
+	VQLite_DropCollection_FullMethodName         = "/vqlite.VQLite/DropCollection"
+	VQLite_RenameCollection_FullMethodName       = "/vqlite.VQLite/RenameCollection"
+	VQLite_CloneCollection_FullMethodName        = "/vqlite.VQLite/CloneCollection"
+	VQLite_TrainCollection_FullMethodName        = "/vqlite.VQLite/TrainCollection"
+	VQLite_SetTrainOpt_FullMethodName            = "/vqlite.VQLite/SetTrainOpt"
+	VQLite_DumpCollection_FullMethodName         = "/vqlite.VQLite/DumpCollection"
+	VQLite_DumpCollectionMetadata_FullMethodName = "/vqlite.VQLite/DumpCollectionMetadata"
+	VQLite_DumpCollectionIndex_FullMethodName    = "/vqlite.VQLite/DumpCollectionIndex"
+	VQLite_LoadCollection_FullMethodName         = "/vqlite.VQLite/LoadCollection"
+	VQLite_UnloadCollection_FullMethodName       = "/vqlite.VQLite/UnloadCollection"
+	VQLite_Search_FullMethodName                 = "/vqlite.VQLite/Search"
+	VQLite_EvalCollection_FullMethodName         = "/vqlite.VQLite/EvalCollection"
+	VQLite_SetSearchOpt_FullMethodName           = "/vqlite.VQLite/SetSearchOpt"
+	VQLite_TuneCollection_FullMethodName         = "/vqlite.VQLite/TuneCollection"
+	VQLite_AddDocument_FullMethodName            = "/vqlite.VQLite/AddDocument"
+	VQLite_BatchAddDocuments_FullMethodName      = "/vqlite.VQLite/BatchAddDocuments"
+	VQLite_DeleteDocument_FullMethodName         = "/vqlite.VQLite/DeleteDocument"
+	VQLite_UpdateDocumentMetadata_FullMethodName = "/vqlite.VQLite/UpdateDocumentMetadata"
+	VQLite_GetDocumentMetadata_FullMethodName    = "/vqlite.VQLite/GetDocumentMetadata"
+	VQLite_ListSnapshots_FullMethodName          = "/vqlite.VQLite/ListSnapshots"
+	VQLite_CreateSnapshot_FullMethodName         = "/vqlite.VQLite/CreateSnapshot"
+	VQLite_RestoreSnapshot_FullMethodName        = "/vqlite.VQLite/RestoreSnapshot"
+	VQLite_DeleteSnapshot_FullMethodName         = "/vqlite.VQLite/DeleteSnapshot"
+	VQLite_ListAliases_FullMethodName            = "/vqlite.VQLite/ListAliases"
+	VQLite_GetAlias_FullMethodName               = "/vqlite.VQLite/GetAlias"
+	VQLite_SetAlias_FullMethodName               = "/vqlite.VQLite/SetAlias"
+	VQLite_DeleteAlias_FullMethodName            = "/vqlite.VQLite/DeleteAlias"
+)
+
+// VQLiteClient is the client API for VQLite service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type VQLiteClient interface {
+	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
+	Statistics(ctx context.Context, in *StatisticsRequest, opts ...grpc.CallOption) (*StatisticsResponse, error)
+	// collections
+	GetCollection(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*CollectionResponse, error)
+	CreateCollection(ctx context.Context, in *CreateCollectionRequest, opts ...grpc.CallOption) (*CollectionResponse, error)
+	DropCollection(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*EmptyResponse, error)
+	RenameCollection(ctx context.Context, in *RenameCollectionRequest, opts ...grpc.CallOption) (*CollectionResponse, error)
+	CloneCollection(ctx context.Context, in *CloneCollectionRequest, opts ...grpc.CallOption) (*CollectionResponse, error)
+	TrainCollection(ctx context.Context, in *TrainRequest, opts ...grpc.CallOption) (*EmptyResponse, error)
+	SetTrainOpt(ctx context.Context, in *SetTrainOptRequest, opts ...grpc.CallOption) (*CollectionResponse, error)
+	DumpCollection(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*EmptyResponse, error)
+	DumpCollectionMetadata(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*EmptyResponse, error)
+	DumpCollectionIndex(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*EmptyResponse, error)
+	LoadCollection(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*EmptyResponse, error)
+	UnloadCollection(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*EmptyResponse, error)
+	// search
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// recall of the search against exact neighbors
+	EvalCollection(ctx context.Context, in *EvalRequest, opts ...grpc.CallOption) (*EvalResponse, error)
+	// stored search options, set by hand or tuned for a target recall
+	SetSearchOpt(ctx context.Context, in *SetSearchOptRequest, opts ...grpc.CallOption) (*CollectionResponse, error)
+	TuneCollection(ctx context.Context, in *TuneRequest, opts ...grpc.CallOption) (*TuneResponse, error)
+	// documents
+	AddDocument(ctx context.Context, in *AddDocumentRequest, opts ...grpc.CallOption) (*EmptyResponse, error)
+	BatchAddDocuments(ctx context.Context, in *BatchAddDocumentsRequest, opts ...grpc.CallOption) (*EmptyResponse, error)
+	DeleteDocument(ctx context.Context, in *DeleteDocumentRequest, opts ...grpc.CallOption) (*CountResponse, error)
+	UpdateDocumentMetadata(ctx context.Context, in *UpdateDocumentMetadataRequest, opts ...grpc.CallOption) (*CountResponse, error)
+	GetDocumentMetadata(ctx context.Context, in *GetDocumentMetadataRequest, opts ...grpc.CallOption) (*GetDocumentMetadataResponse, error)
+	// named snapshots
+	ListSnapshots(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*ListSnapshotsResponse, error)
+	CreateSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error)
+	RestoreSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error)
+	DeleteSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*EmptyResponse, error)
+	// aliases
+	ListAliases(ctx context.Context, in *ListAliasesRequest, opts ...grpc.CallOption) (*ListAliasesResponse, error)
+	GetAlias(ctx context.Context, in *AliasRequest, opts ...grpc.CallOption) (*Alias, error)
+	SetAlias(ctx context.Context, in *SetAliasRequest, opts ...grpc.CallOption) (*Alias, error)
+	DeleteAlias(ctx context.Context, in *AliasRequest, opts ...grpc.CallOption) (*EmptyResponse, error)
+}
+
+type vQLiteClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVQLiteClient(cc grpc.ClientConnInterface) VQLiteClient {
+	return &vQLiteClient{cc}
+}
+
+func (c *vQLiteClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
+	out := new(PingResponse)
+	err := c.cc.Invoke(ctx, VQLite_Ping_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) Statistics(ctx context.Context, in *StatisticsRequest, opts ...grpc.CallOption) (*StatisticsResponse, error) {
+	out := new(StatisticsResponse)
+	err := c.cc.Invoke(ctx, VQLite_Statistics_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) GetCollection(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*CollectionResponse, error) {
+	out := new(CollectionResponse)
+	err := c.cc.Invoke(ctx, VQLite_GetCollection_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) CreateCollection(ctx context.Context, in *CreateCollectionRequest, opts ...grpc.CallOption) (*CollectionResponse, error) {
+	out := new(CollectionResponse)
+	err := c.cc.Invoke(ctx, VQLite_CreateCollection_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) DropCollection(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*EmptyResponse, error) {
+	out := new(EmptyResponse)
+	err := c.cc.Invoke(ctx, VQLite_DropCollection_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) RenameCollection(ctx context.Context, in *RenameCollectionRequest, opts ...grpc.CallOption) (*CollectionResponse, error) {
+	out := new(CollectionResponse)
+	err := c.cc.Invoke(ctx, VQLite_RenameCollection_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) CloneCollection(ctx context.Context, in *CloneCollectionRequest, opts ...grpc.CallOption) (*CollectionResponse, error) {
+	out := new(CollectionResponse)
+	err := c.cc.Invoke(ctx, VQLite_CloneCollection_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) TrainCollection(ctx context.Context, in *TrainRequest, opts ...grpc.CallOption) (*EmptyResponse, error) {
+	out := new(EmptyResponse)
+	err := c.cc.Invoke(ctx, VQLite_TrainCollection_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) SetTrainOpt(ctx context.Context, in *SetTrainOptRequest, opts ...grpc.CallOption) (*CollectionResponse, error) {
+	out := new(CollectionResponse)
+	err := c.cc.Invoke(ctx, VQLite_SetTrainOpt_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) DumpCollection(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*EmptyResponse, error) {
+	out := new(EmptyResponse)
+	err := c.cc.Invoke(ctx, VQLite_DumpCollection_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) DumpCollectionMetadata(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*EmptyResponse, error) {
+	out := new(EmptyResponse)
+	err := c.cc.Invoke(ctx, VQLite_DumpCollectionMetadata_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) DumpCollectionIndex(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*EmptyResponse, error) {
+	out := new(EmptyResponse)
+	err := c.cc.Invoke(ctx, VQLite_DumpCollectionIndex_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) LoadCollection(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*EmptyResponse, error) {
+	out := new(EmptyResponse)
+	err := c.cc.Invoke(ctx, VQLite_LoadCollection_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) UnloadCollection(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*EmptyResponse, error) {
+	out := new(EmptyResponse)
+	err := c.cc.Invoke(ctx, VQLite_UnloadCollection_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, VQLite_Search_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) EvalCollection(ctx context.Context, in *EvalRequest, opts ...grpc.CallOption) (*EvalResponse, error) {
+	out := new(EvalResponse)
+	err := c.cc.Invoke(ctx, VQLite_EvalCollection_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) SetSearchOpt(ctx context.Context, in *SetSearchOptRequest, opts ...grpc.CallOption) (*CollectionResponse, error) {
+	out := new(CollectionResponse)
+	err := c.cc.Invoke(ctx, VQLite_SetSearchOpt_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) TuneCollection(ctx context.Context, in *TuneRequest, opts ...grpc.CallOption) (*TuneResponse, error) {
+	out := new(TuneResponse)
+	err := c.cc.Invoke(ctx, VQLite_TuneCollection_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) AddDocument(ctx context.Context, in *AddDocumentRequest, opts ...grpc.CallOption) (*EmptyResponse, error) {
+	out := new(EmptyResponse)
+	err := c.cc.Invoke(ctx, VQLite_AddDocument_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) BatchAddDocuments(ctx context.Context, in *BatchAddDocumentsRequest, opts ...grpc.CallOption) (*EmptyResponse, error) {
+	out := new(EmptyResponse)
+	err := c.cc.Invoke(ctx, VQLite_BatchAddDocuments_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) DeleteDocument(ctx context.Context, in *DeleteDocumentRequest, opts ...grpc.CallOption) (*CountResponse, error) {
+	out := new(CountResponse)
+	err := c.cc.Invoke(ctx, VQLite_DeleteDocument_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) UpdateDocumentMetadata(ctx context.Context, in *UpdateDocumentMetadataRequest, opts ...grpc.CallOption) (*CountResponse, error) {
+	out := new(CountResponse)
+	err := c.cc.Invoke(ctx, VQLite_UpdateDocumentMetadata_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) GetDocumentMetadata(ctx context.Context, in *GetDocumentMetadataRequest, opts ...grpc.CallOption) (*GetDocumentMetadataResponse, error) {
+	out := new(GetDocumentMetadataResponse)
+	err := c.cc.Invoke(ctx, VQLite_GetDocumentMetadata_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) ListSnapshots(ctx context.Context, in *CollectionRequest, opts ...grpc.CallOption) (*ListSnapshotsResponse, error) {
+	out := new(ListSnapshotsResponse)
+	err := c.cc.Invoke(ctx, VQLite_ListSnapshots_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) CreateSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error) {
+	out := new(Snapshot)
+	err := c.cc.Invoke(ctx, VQLite_CreateSnapshot_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) RestoreSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error) {
+	out := new(Snapshot)
+	err := c.cc.Invoke(ctx, VQLite_RestoreSnapshot_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) DeleteSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*EmptyResponse, error) {
+	out := new(EmptyResponse)
+	err := c.cc.Invoke(ctx, VQLite_DeleteSnapshot_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) ListAliases(ctx context.Context, in *ListAliasesRequest, opts ...grpc.CallOption) (*ListAliasesResponse, error) {
+	out := new(ListAliasesResponse)
+	err := c.cc.Invoke(ctx, VQLite_ListAliases_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) GetAlias(ctx context.Context, in *AliasRequest, opts ...grpc.CallOption) (*Alias, error) {
+	out := new(Alias)
+	err := c.cc.Invoke(ctx, VQLite_GetAlias_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) SetAlias(ctx context.Context, in *SetAliasRequest, opts ...grpc.CallOption) (*Alias, error) {
+	out := new(Alias)
+	err := c.cc.Invoke(ctx, VQLite_SetAlias_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vQLiteClient) DeleteAlias(ctx context.Context, in *AliasRequest, opts ...grpc.CallOption) (*EmptyResponse, error) {
+	out := new(EmptyResponse)
+	err := c.cc.Invoke(ctx, VQLite_DeleteAlias_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VQLiteServer is the server API for VQLite service.
+// All implementations must embed UnimplementedVQLiteServer
+// for forward compatibility
+type VQLiteServer interface {
+	Ping(context.Context, *PingRequest) (*PingResponse, error)
+	Statistics(context.Context, *StatisticsRequest) (*StatisticsResponse, error)
+	// collections
+	GetCollection(context.Context, *CollectionRequest) (*CollectionResponse, error)
+	CreateCollection(context.Context, *CreateCollectionRequest) (*CollectionResponse, error)
+	DropCollection(context.Context, *CollectionRequest) (*EmptyResponse, error)
+	RenameCollection(context.Context, *RenameCollectionRequest) (*CollectionResponse, error)
+	CloneCollection(context.Context, *CloneCollectionRequest) (*CollectionResponse, error)
+	TrainCollection(context.Context, *TrainRequest) (*EmptyResponse, error)
+	SetTrainOpt(context.Context, *SetTrainOptRequest) (*CollectionResponse, error)
+	DumpCollection(context.Context, *CollectionRequest) (*EmptyResponse, error)
+	DumpCollectionMetadata(context.Context, *CollectionRequest) (*EmptyResponse, error)
+	DumpCollectionIndex(context.Context, *CollectionRequest) (*EmptyResponse, error)
+	LoadCollection(context.Context, *CollectionRequest) (*EmptyResponse, error)
+	UnloadCollection(context.Context, *CollectionRequest) (*EmptyResponse, error)
+	// search
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// recall of the search against exact neighbors
+	EvalCollection(context.Context, *EvalRequest) (*EvalResponse, error)
+	// stored search options, set by hand or tuned for a target recall
+	SetSearchOpt(context.Context, *SetSearchOptRequest) (*CollectionResponse, error)
+	TuneCollection(context.Context, *TuneRequest) (*TuneResponse, error)
+	// documents
+	AddDocument(context.Context, *AddDocumentRequest) (*EmptyResponse, error)
+	BatchAddDocuments(context.Context, *BatchAddDocumentsRequest) (*EmptyResponse, error)
+	DeleteDocument(context.Context, *DeleteDocumentRequest) (*CountResponse, error)
+	UpdateDocumentMetadata(context.Context, *UpdateDocumentMetadataRequest) (*CountResponse, error)
+	GetDocumentMetadata(context.Context, *GetDocumentMetadataRequest) (*GetDocumentMetadataResponse, error)
+	// named snapshots
+	ListSnapshots(context.Context, *CollectionRequest) (*ListSnapshotsResponse, error)
+	CreateSnapshot(context.Context, *SnapshotRequest) (*Snapshot, error)
+	RestoreSnapshot(context.Context, *SnapshotRequest) (*Snapshot, error)
+	DeleteSnapshot(context.Context, *SnapshotRequest) (*EmptyResponse, error)
+	// aliases
+	ListAliases(context.Context, *ListAliasesRequest) (*ListAliasesResponse, error)
+	GetAlias(context.Context, *AliasRequest) (*Alias, error)
+	SetAlias(context.Context, *SetAliasRequest) (*Alias, error)
+	DeleteAlias(context.Context, *AliasRequest) (*EmptyResponse, error)
+	mustEmbedUnimplementedVQLiteServer()
+}
+
+// UnimplementedVQLiteServer must be embedded to have forward compatible implementations.
+type UnimplementedVQLiteServer struct {
+}
+
+func (UnimplementedVQLiteServer) Ping(context.Context, *PingRequest) (*PingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (UnimplementedVQLiteServer) Statistics(context.Context, *StatisticsRequest) (*StatisticsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Statistics not implemented")
+}
+func (UnimplementedVQLiteServer) GetCollection(context.Context, *CollectionRequest) (*CollectionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCollection not implemented")
+}
+func (UnimplementedVQLiteServer) CreateCollection(context.Context, *CreateCollectionRequest) (*CollectionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCollection not implemented")
+}
+func (UnimplementedVQLiteServer) DropCollection(context.Context, *CollectionRequest) (*EmptyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DropCollection not implemented")
+}
+func (UnimplementedVQLiteServer) RenameCollection(context.Context, *RenameCollectionRequest) (*CollectionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenameCollection not implemented")
+}
+func (UnimplementedVQLiteServer) CloneCollection(context.Context, *CloneCollectionRequest) (*CollectionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloneCollection not implemented")
+}
+func (UnimplementedVQLiteServer) TrainCollection(context.Context, *TrainRequest) (*EmptyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TrainCollection not implemented")
+}
+func (UnimplementedVQLiteServer) SetTrainOpt(context.Context, *SetTrainOptRequest) (*CollectionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetTrainOpt not implemented")
+}
+func (UnimplementedVQLiteServer) DumpCollection(context.Context, *CollectionRequest) (*EmptyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DumpCollection not implemented")
+}
+func (UnimplementedVQLiteServer) DumpCollectionMetadata(context.Context, *CollectionRequest) (*EmptyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DumpCollectionMetadata not implemented")
+}
+func (UnimplementedVQLiteServer) DumpCollectionIndex(context.Context, *CollectionRequest) (*EmptyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DumpCollectionIndex not implemented")
+}
+func (UnimplementedVQLiteServer) LoadCollection(context.Context, *CollectionRequest) (*EmptyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LoadCollection not implemented")
+}
+func (UnimplementedVQLiteServer) UnloadCollection(context.Context, *CollectionRequest) (*EmptyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnloadCollection not implemented")
+}
+func (UnimplementedVQLiteServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedVQLiteServer) EvalCollection(context.Context, *EvalRequest) (*EvalResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EvalCollection not implemented")
+}
+func (UnimplementedVQLiteServer) SetSearchOpt(context.Context, *SetSearchOptRequest) (*CollectionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetSearchOpt not implemented")
+}
+func (UnimplementedVQLiteServer) TuneCollection(context.Context, *TuneRequest) (*TuneResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TuneCollection not implemented")
+}
+func (UnimplementedVQLiteServer) AddDocument(context.Context, *AddDocumentRequest) (*EmptyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddDocument not implemented")
+}
+func (UnimplementedVQLiteServer) BatchAddDocuments(context.Context, *BatchAddDocumentsRequest) (*EmptyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchAddDocuments not implemented")
+}
+func (UnimplementedVQLiteServer) DeleteDocument(context.Context, *DeleteDocumentRequest) (*CountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteDocument not implemented")
+}
+func (UnimplementedVQLiteServer) UpdateDocumentMetadata(context.Context, *UpdateDocumentMetadataRequest) (*CountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateDocumentMetadata not implemented")
+}
+func (UnimplementedVQLiteServer) GetDocumentMetadata(context.Context, *GetDocumentMetadataRequest) (*GetDocumentMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDocumentMetadata not implemented")
+}
+func (UnimplementedVQLiteServer) ListSnapshots(context.Context, *CollectionRequest) (*ListSnapshotsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSnapshots not implemented")
+}
+func (UnimplementedVQLiteServer) CreateSnapshot(context.Context, *SnapshotRequest) (*Snapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSnapshot not implemented")
+}
+func (UnimplementedVQLiteServer) RestoreSnapshot(context.Context, *SnapshotRequest) (*Snapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreSnapshot not implemented")
+}
+func (UnimplementedVQLiteServer) DeleteSnapshot(context.Context, *SnapshotRequest) (*EmptyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSnapshot not implemented")
+}
+func (UnimplementedVQLiteServer) ListAliases(context.Context, *ListAliasesRequest) (*ListAliasesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAliases not implemented")
+}
+func (UnimplementedVQLiteServer) GetAlias(context.Context, *AliasRequest) (*Alias, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAlias not implemented")
+}
+func (UnimplementedVQLiteServer) SetAlias(context.Context, *SetAliasRequest) (*Alias, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetAlias not implemented")
+}
+func (UnimplementedVQLiteServer) DeleteAlias(context.Context, *AliasRequest) (*EmptyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAlias not implemented")
+}
+func (UnimplementedVQLiteServer) mustEmbedUnimplementedVQLiteServer() {}
+
+// UnsafeVQLiteServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VQLiteServer will
+// result in compilation errors.
+type UnsafeVQLiteServer interface {
+	mustEmbedUnimplementedVQLiteServer()
+}
+
+func RegisterVQLiteServer(s grpc.ServiceRegistrar, srv VQLiteServer) {
+	s.RegisterService(&VQLite_ServiceDesc, srv)
+}
+
+func _VQLite_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_Ping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).Ping(ctx, req.(*PingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_Statistics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatisticsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).Statistics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_Statistics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).Statistics(ctx, req.(*StatisticsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_GetCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).GetCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_GetCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).GetCollection(ctx, req.(*CollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_CreateCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).CreateCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_CreateCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).CreateCollection(ctx, req.(*CreateCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_DropCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).DropCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_DropCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).DropCollection(ctx, req.(*CollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_RenameCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenameCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).RenameCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_RenameCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).RenameCollection(ctx, req.(*RenameCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_CloneCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloneCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).CloneCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_CloneCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).CloneCollection(ctx, req.(*CloneCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_TrainCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).TrainCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_TrainCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).TrainCollection(ctx, req.(*TrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_SetTrainOpt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetTrainOptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).SetTrainOpt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_SetTrainOpt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).SetTrainOpt(ctx, req.(*SetTrainOptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_DumpCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).DumpCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_DumpCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).DumpCollection(ctx, req.(*CollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_DumpCollectionMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).DumpCollectionMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_DumpCollectionMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).DumpCollectionMetadata(ctx, req.(*CollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_DumpCollectionIndex_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).DumpCollectionIndex(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_DumpCollectionIndex_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).DumpCollectionIndex(ctx, req.(*CollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_LoadCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).LoadCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_LoadCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).LoadCollection(ctx, req.(*CollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_UnloadCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).UnloadCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_UnloadCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).UnloadCollection(ctx, req.(*CollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_EvalCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).EvalCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_EvalCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).EvalCollection(ctx, req.(*EvalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_SetSearchOpt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetSearchOptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).SetSearchOpt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_SetSearchOpt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).SetSearchOpt(ctx, req.(*SetSearchOptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_TuneCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TuneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).TuneCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_TuneCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).TuneCollection(ctx, req.(*TuneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_AddDocument_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddDocumentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).AddDocument(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_AddDocument_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).AddDocument(ctx, req.(*AddDocumentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_BatchAddDocuments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchAddDocumentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).BatchAddDocuments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_BatchAddDocuments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).BatchAddDocuments(ctx, req.(*BatchAddDocumentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_DeleteDocument_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteDocumentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).DeleteDocument(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_DeleteDocument_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).DeleteDocument(ctx, req.(*DeleteDocumentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_UpdateDocumentMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateDocumentMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).UpdateDocumentMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_UpdateDocumentMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).UpdateDocumentMetadata(ctx, req.(*UpdateDocumentMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_GetDocumentMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDocumentMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).GetDocumentMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_GetDocumentMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).GetDocumentMetadata(ctx, req.(*GetDocumentMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_ListSnapshots_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).ListSnapshots(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_ListSnapshots_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).ListSnapshots(ctx, req.(*CollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_CreateSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).CreateSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_CreateSnapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).CreateSnapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_RestoreSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).RestoreSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_RestoreSnapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).RestoreSnapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_DeleteSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).DeleteSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_DeleteSnapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).DeleteSnapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_ListAliases_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAliasesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).ListAliases(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_ListAliases_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).ListAliases(ctx, req.(*ListAliasesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_GetAlias_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AliasRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).GetAlias(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_GetAlias_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).GetAlias(ctx, req.(*AliasRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_SetAlias_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetAliasRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).SetAlias(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_SetAlias_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).SetAlias(ctx, req.(*SetAliasRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VQLite_DeleteAlias_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AliasRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VQLiteServer).DeleteAlias(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VQLite_DeleteAlias_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VQLiteServer).DeleteAlias(ctx, req.(*AliasRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// VQLite_ServiceDesc is the grpc.ServiceDesc for VQLite service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var VQLite_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "vqlite.VQLite",
+	HandlerType: (*VQLiteServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Ping",
+			Handler:    _VQLite_Ping_Handler,
+		},
+		{
+			MethodName: "Statistics",
+			Handler:    _VQLite_Statistics_Handler,
+		},
+		{
+			MethodName: "GetCollection",
+			Handler:    _VQLite_GetCollection_Handler,
+		},
+		{
+			MethodName: "CreateCollection",
+			Handler:    _VQLite_CreateCollection_Handler,
+		},
+		{
+			MethodName: "DropCollection",
+			Handler:    _VQLite_DropCollection_Handler,
+		},
+		{
+			MethodName: "RenameCollection",
+			Handler:    _VQLite_RenameCollection_Handler,
+		},
+		{
+			MethodName: "CloneCollection",
+			Handler:    _VQLite_CloneCollection_Handler,
+		},
+		{
+			MethodName: "TrainCollection",
+			Handler:    _VQLite_TrainCollection_Handler,
+		},
+		{
+			MethodName: "SetTrainOpt",
+			Handler:    _VQLite_SetTrainOpt_Handler,
+		},
+		{
+			MethodName: "DumpCollection",
+			Handler:    _VQLite_DumpCollection_Handler,
+		},
+		{
+			MethodName: "DumpCollectionMetadata",
+			Handler:    _VQLite_DumpCollectionMetadata_Handler,
+		},
+		{
+			MethodName: "DumpCollectionIndex",
+			Handler:    _VQLite_DumpCollectionIndex_Handler,
+		},
+		{
+			MethodName: "LoadCollection",
+			Handler:    _VQLite_LoadCollection_Handler,
+		},
+		{
+			MethodName: "UnloadCollection",
+			Handler:    _VQLite_UnloadCollection_Handler,
+		},
+		{
+			MethodName: "Search",
+			Handler:    _VQLite_Search_Handler,
+		},
+		{
+			MethodName: "EvalCollection",
+			Handler:    _VQLite_EvalCollection_Handler,
+		},
+		{
+			MethodName: "SetSearchOpt",
+			Handler:    _VQLite_SetSearchOpt_Handler,
+		},
+		{
+			MethodName: "TuneCollection",
+			Handler:    _VQLite_TuneCollection_Handler,
+		},
+		{
+			MethodName: "AddDocument",
+			Handler:    _VQLite_AddDocument_Handler,
+		},
+		{
+			MethodName: "BatchAddDocuments",
+			Handler:    _VQLite_BatchAddDocuments_Handler,
+		},
+		{
+			MethodName: "DeleteDocument",
+			Handler:    _VQLite_DeleteDocument_Handler,
+		},
+		{
+			MethodName: "UpdateDocumentMetadata",
+			Handler:    _VQLite_UpdateDocumentMetadata_Handler,
+		},
+		{
+			MethodName: "GetDocumentMetadata",
+			Handler:    _VQLite_GetDocumentMetadata_Handler,
+		},
+		{
+			MethodName: "ListSnapshots",
+			Handler:    _VQLite_ListSnapshots_Handler,
+		},
+		{
+			MethodName: "CreateSnapshot",
+			Handler:    _VQLite_CreateSnapshot_Handler,
+		},
+		{
+			MethodName: "RestoreSnapshot",
+			Handler:    _VQLite_RestoreSnapshot_Handler,
+		},
+		{
+			MethodName: "DeleteSnapshot",
+			Handler:    _VQLite_DeleteSnapshot_Handler,
+		},
+		{
+			MethodName: "ListAliases",
+			Handler:    _VQLite_ListAliases_Handler,
+		},
+		{
+			MethodName: "GetAlias",
+			Handler:    _VQLite_GetAlias_Handler,
+		},
+		{
+			MethodName: "SetAlias",
+			Handler:    _VQLite_SetAlias_Handler,
+		},
+		{
+			MethodName: "DeleteAlias",
+			Handler:    _VQLite_DeleteAlias_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "vqlite.proto",
+}
@@ -73,6 +73,10 @@ class Vqlite:
         response = self.request('GET', self.api_url + '/statistics')
         return json.loads(response.text)
 
+    def get_collection(self, collection_name):
+        response = self.request('GET', self.api_url + f'/collection/{collection_name}')
+        return json.loads(response.text)
+
     def drop_collection(self, collection_name):
         response = self.request('DELETE', self.api_url + f'/collection/{collection_name}')
         return json.loads(response.text)
@@ -93,6 +97,72 @@ class Vqlite:
         response = self.request('POST', self.api_url + f'/collection/{collection_name}/load')
         return json.loads(response.text)
 
+    def snapshot_collection(self, collection_name, output_file):
+        response = self.request('POST', self.api_url + f'/collection/{collection_name}/snapshot', stream=True)
+        if response.status_code != 200:
+            return json.loads(response.text)
+        with open(output_file, 'wb') as f:
+            for chunk in response.iter_content(chunk_size=1024 * 1024):
+                f.write(chunk)
+        return {"status": "ok"}
+
+    def restore_collection(self, collection_name, input_file):
+        with open(input_file, 'rb') as f:
+            response = self.request('POST', self.api_url + f'/collection/{collection_name}/restore', data=f)
+        return json.loads(response.text)
+
+    def create_snapshot(self, collection_name, snapshot_name=None):
+        payload = {}
+        if snapshot_name:
+            payload['name'] = snapshot_name
+        payload_json = json.dumps(payload)
+        response = self.request('POST', self.api_url + f'/collection/{collection_name}/snapshots', data=payload_json)
+        return json.loads(response.text)
+
+    def list_snapshots(self, collection_name):
+        response = self.request('GET', self.api_url + f'/collection/{collection_name}/snapshots')
+        return json.loads(response.text)
+
+    def restore_snapshot(self, collection_name, snapshot_name):
+        response = self.request('POST',
+                                self.api_url + f'/collection/{collection_name}/snapshots/{snapshot_name}/restore')
+        return json.loads(response.text)
+
+    def delete_snapshot(self, collection_name, snapshot_name):
+        response = self.request('DELETE', self.api_url + f'/collection/{collection_name}/snapshots/{snapshot_name}')
+        return json.loads(response.text)
+
+    def rename_collection(self, collection_name, new_name):
+        payload_json = json.dumps({'name': new_name})
+        response = self.request('POST', self.api_url + f'/collection/{collection_name}/rename', data=payload_json)
+        return json.loads(response.text)
+
+    def clone_collection(self, collection_name, new_name):
+        payload_json = json.dumps({'name': new_name})
+        response = self.request('POST', self.api_url + f'/collection/{collection_name}/clone', data=payload_json)
+        return json.loads(response.text)
+
+    def set_alias(self, alias, collection_name):
+        payload_json = json.dumps({'collection': collection_name})
+        response = self.request('PUT', self.api_url + f'/alias/{alias}', data=payload_json)
+        return json.loads(response.text)
+
+    def get_alias(self, alias):
+        response = self.request('GET', self.api_url + f'/alias/{alias}')
+        return json.loads(response.text)
+
+    def list_aliases(self):
+        response = self.request('GET', self.api_url + '/alias')
+        return json.loads(response.text)
+
+    def delete_alias(self, alias):
+        response = self.request('DELETE', self.api_url + f'/alias/{alias}')
+        return json.loads(response.text)
+
+    def unload_collection(self, collection_name):
+        response = self.request('POST', self.api_url + f'/collection/{collection_name}/unload')
+        return json.loads(response.text)
+
     def create_collection(self, collection_name, dim):
         payload = {
             'name': collection_name,
@@ -0,0 +1,159 @@
+// Package ratelimit limits the request rate of every client per route class and the number of
+// concurrent searches, it is used by the HTTP middleware and the gRPC interceptor alike.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/time/rate"
+	"math"
+	"sync"
+	"time"
+	"vqlite/config"
+	vqerrors "vqlite/errors"
+)
+
+// Class groups routes sharing a rate limit.
+type Class string
+
+const (
+	ClassSearch Class = "search"
+	ClassRead   Class = "read"
+	ClassWrite  Class = "write"
+	ClassAdmin  Class = "admin"
+)
+
+// limiterIdleTimeout drops the limiters of clients without requests for this long.
+const limiterIdleTimeout = 10 * time.Minute
+
+// RateLimitedError is returned for requests above the rate limit or the search admission limit.
+type RateLimitedError struct {
+	Class      Class
+	Client     string
+	RetryAfter int // seconds
+}
+
+func (e *RateLimitedError) ErrorCode() vqerrors.Code {
+	return vqerrors.CodeRateLimited
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.Client == "" {
+		return fmt.Sprintf("too many concurrent %s requests", e.Class)
+	}
+	return fmt.Sprintf("rate limit of %s requests exceeded for client [%s]", e.Class, e.Client)
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+var (
+	lock     sync.Mutex
+	limiters = make(map[Class]map[string]*clientLimiter)
+	limits   map[Class]config.RateLimit
+
+	// searchSlots admits a search when it can send to it, nil when searches are not limited
+	searchSlots  chan struct{}
+	queueTimeout time.Duration
+
+	dropIdleOnce sync.Once
+)
+
+// Init sets the limits of the config, it must be called before serving requests.
+func Init() {
+	rateLimitConfig := config.GlobalConfig.RateLimitConfig
+	limits = map[Class]config.RateLimit{
+		ClassSearch: rateLimitConfig.Search,
+		ClassRead:   rateLimitConfig.Read,
+		ClassWrite:  rateLimitConfig.Write,
+		ClassAdmin:  rateLimitConfig.Admin,
+	}
+	searchSlots = nil
+	if rateLimitConfig.MaxConcurrentSearches > 0 {
+		searchSlots = make(chan struct{}, rateLimitConfig.MaxConcurrentSearches)
+	}
+	queueTimeout = time.Duration(rateLimitConfig.SearchQueueTimeout) * time.Millisecond
+
+	dropIdleOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(limiterIdleTimeout)
+			defer ticker.Stop()
+			for range ticker.C {
+				dropIdleLimiters()
+			}
+		}()
+	})
+}
+
+func dropIdleLimiters() {
+	lock.Lock()
+	defer lock.Unlock()
+	for _, clients := range limiters {
+		for client, l := range clients {
+			if time.Since(l.lastSeen) > limiterIdleTimeout {
+				delete(clients, client)
+			}
+		}
+	}
+}
+
+// Allow takes a token from the bucket of client for class, client is the API key name or the IP.
+// Classes with a rate of 0 are not limited.
+func Allow(class Class, client string) error {
+	limit := limits[class]
+	if limit.Rate <= 0 {
+		return nil
+	}
+
+	lock.Lock()
+	clients, ok := limiters[class]
+	if !ok {
+		clients = make(map[string]*clientLimiter)
+		limiters[class] = clients
+	}
+	l, ok := clients[client]
+	if !ok {
+		burst := limit.Burst
+		if burst <= 0 {
+			burst = int(math.Ceil(limit.Rate))
+		}
+		l = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(limit.Rate), burst)}
+		clients[client] = l
+	}
+	l.lastSeen = time.Now()
+	lock.Unlock()
+
+	reservation := l.limiter.Reserve()
+	delay := reservation.Delay()
+	if delay == 0 {
+		return nil
+	}
+	reservation.Cancel()
+	return &RateLimitedError{Class: class, Client: client, RetryAfter: int(math.Ceil(delay.Seconds()))}
+}
+
+// AdmitSearch waits up to the queue timeout for a search slot, the returned func releases it.
+func AdmitSearch(ctx context.Context) (func(), error) {
+	if searchSlots == nil {
+		return func() {}, nil
+	}
+	release := func() { <-searchSlots }
+	select {
+	case searchSlots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	timer := time.NewTimer(queueTimeout)
+	defer timer.Stop()
+	select {
+	case searchSlots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, &RateLimitedError{Class: ClassSearch, RetryAfter: 1}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
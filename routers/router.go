@@ -5,14 +5,30 @@ import (
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+	"vqlite/auth"
 	"vqlite/config"
 	"vqlite/core"
 	"vqlite/handlers"
+	"vqlite/middlewares"
+	"vqlite/ratelimit"
 )
 
 func InitRouter() *gin.Engine {
 	// load all collections
 	core.LoadAllCollections()
+	// unload idle collections and collections above the memory budget
+	core.StartCollectionEvictor()
+	// dump changed segments periodically
+	core.StartCollectionFlusher()
+	// train segments with a few new vectors incrementally
+	core.StartIncrementalTrainer()
+	// load the API keys
+	if err := auth.Init(); err != nil {
+		log.Fatal().Err(err).Msg("init auth error")
+	}
+	// rate limits per client and route class
+	ratelimit.Init()
 
 	gin.SetMode(gin.ReleaseMode)
 
@@ -25,42 +41,90 @@ func InitRouter() *gin.Engine {
 		r = gin.Default()
 	}
 
+	// /metrics and /debug/pprof take the debug keys when auth is enabled
+	debug := r.Group("", middlewares.ErrorResponse(), middlewares.AuthenticateDebug())
 	// register the `/metrics` route.
-	r.GET("/metrics", ginprom.PromHandler(promhttp.Handler()))
+	debug.GET("/metrics", ginprom.PromHandler(promhttp.Handler()))
 
 	api := r.Group("/api")
+	// write errors with the HTTP status and body matching their code
+	api.Use(middlewares.ErrorResponse())
+	// limit JSON bodies, snapshot archives may be larger
+	api.Use(middlewares.LimitBody("/api/collection/:target/restore"))
+	// every :target may be an alias of a collection
+	api.Use(middlewares.ResolveAlias())
+	// health check, open for load balancers
+	api.GET("/ping", handlers.GetHealth)
+
+	// every other route needs an API key with the role, when auth is enabled
+	reader := middlewares.RequireRole(auth.RoleReader)
+	writer := middlewares.RequireRole(auth.RoleWriter)
+	admin := middlewares.RequireRole(auth.RoleAdmin)
+	// rate limits per client
+	searchLimit := middlewares.RateLimit(ratelimit.ClassSearch)
+	readLimit := middlewares.RateLimit(ratelimit.ClassRead)
+	writeLimit := middlewares.RateLimit(ratelimit.ClassWrite)
+	adminLimit := middlewares.RateLimit(ratelimit.ClassAdmin)
+	admitSearch := middlewares.AdmitSearch()
+	api.Use(middlewares.Authenticate())
 	{
-		// health check
-		api.GET("/ping", handlers.GetHealth)
 		// all vqlite stat
-		api.GET("/stat", handlers.VQLiteStatistics)
-		api.GET("/statistics", handlers.VQLiteStatistics)
+		api.GET("/stat", reader, readLimit, handlers.VQLiteStatistics)
+		api.GET("/statistics", reader, readLimit, handlers.VQLiteStatistics)
+		// collection statistics and load state
+		api.GET("/collection/:target", reader, readLimit, handlers.GetCollection)
 		// create colletion
-		api.POST("/collection/:target", handlers.CreateCollection)
+		api.POST("/collection/:target", admin, adminLimit, handlers.CreateCollection)
 		// delete collection
-		api.DELETE("/collection/:target", handlers.DropCollection)
+		api.DELETE("/collection/:target", admin, adminLimit, handlers.DropCollection)
+		// rename and clone collection
+		api.POST("/collection/:target/rename", admin, adminLimit, handlers.RenameCollection)
+		api.POST("/collection/:target/clone", admin, adminLimit, handlers.CloneCollection)
 		// search
-		api.POST("/collection/:target/search", handlers.SearchCollection)
+		api.POST("/collection/:target/search", reader, searchLimit, admitSearch, handlers.SearchCollection)
 		// train
-		api.POST("/collection/:target/train", handlers.TrainCollection)
+		api.POST("/collection/:target/train", writer, writeLimit, handlers.TrainCollection)
+		// stored training parameters
+		api.PUT("/collection/:target/train_opt", writer, adminLimit, handlers.SetTrainOpt)
+		// recall of the search against exact neighbors, eval and tune run many searches so they take a search slot
+		api.POST("/collection/:target/eval", writer, adminLimit, admitSearch, handlers.EvalCollection)
+		// stored search options, set by hand or tuned for a target recall
+		api.PUT("/collection/:target/search_opt", writer, adminLimit, handlers.SetSearchOpt)
+		api.POST("/collection/:target/tune", writer, adminLimit, admitSearch, handlers.TuneCollection)
 
 		// dump collection
 		//api.POST("/collection/:target/dump", handlers.DumpCollection)
-		api.POST("/collection/:target/dump", handlers.DumpCollection)
-		api.POST("/collection/:target/dump/metadata", handlers.DumpCollectionMetadata)
-		api.POST("/collection/:target/dump/index", handlers.DumpCollectionIndex)
+		api.POST("/collection/:target/dump", writer, adminLimit, handlers.DumpCollection)
+		api.POST("/collection/:target/dump/metadata", writer, adminLimit, handlers.DumpCollectionMetadata)
+		api.POST("/collection/:target/dump/index", writer, adminLimit, handlers.DumpCollectionIndex)
 		// load collection
-		api.POST("/collection/:target/load", handlers.LoadCollection)
+		api.POST("/collection/:target/load", writer, adminLimit, handlers.LoadCollection)
+		// unload collection from memory
+		api.POST("/collection/:target/unload", writer, adminLimit, handlers.UnloadCollection)
+		// snapshot collection to a tar archive and restore it
+		api.POST("/collection/:target/snapshot", writer, adminLimit, handlers.SnapshotCollection)
+		api.POST("/collection/:target/restore", admin, adminLimit, handlers.RestoreCollection)
+		// named snapshots kept under the data path
+		api.GET("/collection/:target/snapshots", reader, readLimit, handlers.ListSnapshots)
+		api.POST("/collection/:target/snapshots", writer, adminLimit, handlers.CreateSnapshot)
+		api.POST("/collection/:target/snapshots/:snapshot/restore", admin, adminLimit, handlers.RestoreSnapshot)
+		api.DELETE("/collection/:target/snapshots/:snapshot", admin, adminLimit, handlers.DeleteSnapshot)
 
 		// docs
-		api.POST("/collection/:target/document", handlers.AddDocument)
-		api.POST("/collection/:target/document/batch", handlers.BatchAddDocuments)
-		api.DELETE("/collection/:target/document", handlers.DeleteDocument)
-		api.PUT("/collection/:target/document", handlers.UpdateDocumentMetadata)
-		api.GET("/collection/:target/document", handlers.GetDocumentMetadata)
+		api.POST("/collection/:target/document", writer, writeLimit, handlers.AddDocument)
+		api.POST("/collection/:target/document/batch", writer, writeLimit, handlers.BatchAddDocuments)
+		api.DELETE("/collection/:target/document", writer, writeLimit, handlers.DeleteDocument)
+		api.PUT("/collection/:target/document", writer, writeLimit, handlers.UpdateDocumentMetadata)
+		api.GET("/collection/:target/document", reader, readLimit, handlers.GetDocumentMetadata)
+
+		// alias
+		api.GET("/alias", reader, readLimit, handlers.ListAliases)
+		api.GET("/alias/:name", reader, readLimit, handlers.GetAlias)
+		api.PUT("/alias/:name", admin, adminLimit, handlers.SetAlias)
+		api.DELETE("/alias/:name", admin, adminLimit, handlers.DeleteAlias)
 	}
 
-	pprof.Register(r)
+	pprof.RouteRegister(debug)
 	r.Use(gin.Recovery())
 
 	return r
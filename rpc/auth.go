@@ -0,0 +1,90 @@
+package rpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strings"
+	"vqlite/auth"
+	vqerrors "vqlite/errors"
+	pb "vqlite/proto"
+)
+
+// methodRoles is the role of every method, the same as for the HTTP route.
+var methodRoles = map[string]auth.Role{
+	"Ping":                   auth.RoleNone,
+	"Statistics":             auth.RoleReader,
+	"GetCollection":          auth.RoleReader,
+	"CreateCollection":       auth.RoleAdmin,
+	"DropCollection":         auth.RoleAdmin,
+	"RenameCollection":       auth.RoleAdmin,
+	"CloneCollection":        auth.RoleAdmin,
+	"TrainCollection":        auth.RoleWriter,
+	"DumpCollection":         auth.RoleWriter,
+	"DumpCollectionMetadata": auth.RoleWriter,
+	"DumpCollectionIndex":    auth.RoleWriter,
+	"LoadCollection":         auth.RoleWriter,
+	"UnloadCollection":       auth.RoleWriter,
+	"Search":                 auth.RoleReader,
+	"EvalCollection":         auth.RoleWriter,
+	"SetSearchOpt":           auth.RoleWriter,
+	"SetTrainOpt":            auth.RoleWriter,
+	"TuneCollection":         auth.RoleWriter,
+	"AddDocument":            auth.RoleWriter,
+	"BatchAddDocuments":      auth.RoleWriter,
+	"DeleteDocument":         auth.RoleWriter,
+	"UpdateDocumentMetadata": auth.RoleWriter,
+	"GetDocumentMetadata":    auth.RoleReader,
+	"ListSnapshots":          auth.RoleReader,
+	"CreateSnapshot":         auth.RoleWriter,
+	"RestoreSnapshot":        auth.RoleAdmin,
+	"DeleteSnapshot":         auth.RoleAdmin,
+	"ListAliases":            auth.RoleReader,
+	"GetAlias":               auth.RoleReader,
+	"SetAlias":               auth.RoleAdmin,
+	"DeleteAlias":            auth.RoleAdmin,
+}
+
+// collectionRequest is implemented by the requests on a single collection.
+type collectionRequest interface {
+	GetCollection() string
+}
+
+// authInterceptor checks the API key in the `authorization: Bearer <key>` or `x-api-key` metadata,
+// like middlewares.Authenticate and middlewares.RequireRole. Alias requests count as requests on
+// no single collection, so scoped keys may only read them.
+func authInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !auth.Enabled() {
+		return handler(ctx, req)
+	}
+	role, ok := methodRoles[info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]]
+	if !ok {
+		return nil, vqerrors.PermissionDenied("method [%s] is not allowed", info.FullMethod)
+	}
+	if role == auth.RoleNone {
+		return handler(ctx, req)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	key, err := auth.Authenticate(auth.Token(firstValue(md, "authorization"), firstValue(md, "x-api-key")))
+	if err != nil {
+		return nil, err
+	}
+	collectionName := ""
+	if _, isAlias := req.(*pb.SetAliasRequest); !isAlias {
+		if r, ok := req.(collectionRequest); ok {
+			collectionName = resolve(r.GetCollection())
+		}
+	}
+	if err := key.Authorize(role, collectionName); err != nil {
+		return nil, err
+	}
+	return handler(auth.NewContext(ctx, key), req)
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
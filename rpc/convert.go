@@ -0,0 +1,323 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"vqlite/core"
+	vqerrors "vqlite/errors"
+	pb "vqlite/proto"
+)
+
+// decodeVectors decodes the little endian float32 rows of v back to back in one slice.
+func decodeVectors(v *pb.Vectors) ([]float32, error) {
+	if v == nil || v.Dim == 0 || len(v.Data) == 0 {
+		return nil, vqerrors.InvalidArgument("vectors is empty")
+	}
+	if len(v.Data)%(4*int(v.Dim)) != 0 {
+		return nil, vqerrors.InvalidArgument("vectors data size %d is not a multiple of dim %d * 4", len(v.Data), v.Dim)
+	}
+	flattened := make([]float32, len(v.Data)/4)
+	for i := range flattened {
+		flattened[i] = math.Float32frombits(binary.LittleEndian.Uint32(v.Data[i*4:]))
+	}
+	return flattened, nil
+}
+
+// decodeVectorRows decodes v into one slice per vector, the rows share the decoded slice.
+func decodeVectorRows(v *pb.Vectors) ([][]float32, error) {
+	flattened, err := decodeVectors(v)
+	if err != nil {
+		return nil, err
+	}
+	dim := int(v.Dim)
+	rows := make([][]float32, 0, len(flattened)/dim)
+	for i := 0; i < len(flattened); i += dim {
+		rows = append(rows, flattened[i:i+dim:i+dim])
+	}
+	return rows, nil
+}
+
+func decodeMetadata(data []byte) (map[string]interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, vqerrors.Wrap(vqerrors.CodeInvalidArgument, err, "metadata is not a JSON object")
+	}
+	return metadata, nil
+}
+
+func decodeDocument(doc *pb.Document) (*core.AddDocumentRequest, error) {
+	if doc == nil {
+		return nil, vqerrors.InvalidArgument("document is empty")
+	}
+	vectors, err := decodeVectorRows(doc.Vectors)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := decodeMetadata(doc.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &core.AddDocumentRequest{
+		Vqid:       doc.Vqid,
+		Metadata:   metadata,
+		Vectors:    vectors,
+		VectorsTag: doc.VectorsTag,
+	}, nil
+}
+
+func encodeSearchResponse(result *core.SearchResponse) *pb.SearchResponse {
+	resp := &pb.SearchResponse{Results: make([]*pb.SearchResults, 0, len(result.Results)), Partial: result.Partial}
+	for _, failed := range result.FailedSegments {
+		resp.FailedSegments = append(resp.FailedSegments, &pb.FailedSegment{
+			SegmentId: failed.SegmentId,
+			Code:      string(failed.Code),
+			Error:     failed.Error,
+		})
+	}
+	if result.Debug != nil {
+		resp.Debug = encodeSearchDebug(result.Debug)
+	}
+	for _, queryResults := range result.Results {
+		pbResults := &pb.SearchResults{Results: make([]*pb.SearchResult, 0, len(queryResults))}
+		for _, result := range queryResults {
+			metadata, _ := json.Marshal(result.Metadata)
+			pbResults.Results = append(pbResults.Results, &pb.SearchResult{
+				Vqid:     result.Vqid,
+				Score:    result.Score,
+				Metadata: metadata,
+				Tag:      result.Tag,
+			})
+		}
+		resp.Results = append(resp.Results, pbResults)
+	}
+	return resp
+}
+
+func decodeQueryOpt(opt *pb.QueryOpt) core.QueryOpt {
+	if opt == nil {
+		return core.QueryOpt{}
+	}
+	return core.QueryOpt{
+		TopK:         int(opt.Topk),
+		NProbe:       int(opt.Nprobe),
+		Reorder:      int(opt.Reorder),
+		Timeout:      int(opt.Timeout),
+		NProbeRatio:  opt.NprobeRatio,
+		EfSearch:     int(opt.EfSearch),
+		AllowPartial: opt.AllowPartial,
+		Debug:        opt.Debug,
+	}
+}
+
+func encodeQueryOpt(opt core.QueryOpt) *pb.QueryOpt {
+	return &pb.QueryOpt{
+		Topk:         int32(opt.TopK),
+		Nprobe:       int32(opt.NProbe),
+		Reorder:      int32(opt.Reorder),
+		Timeout:      int32(opt.Timeout),
+		NprobeRatio:  opt.NProbeRatio,
+		EfSearch:     int32(opt.EfSearch),
+		AllowPartial: opt.AllowPartial,
+		Debug:        opt.Debug,
+	}
+}
+
+func decodeTrainOpt(opt *pb.TrainOpt) core.TrainOpt {
+	if opt == nil {
+		return core.TrainOpt{}
+	}
+	return core.TrainOpt{
+		TrainType:                   opt.TrainType,
+		Nlist:                       opt.Nlist,
+		BruteThreshold:              opt.BruteThreshold,
+		PartitioningTrainSampleRate: opt.PartitioningTrainSampleRate,
+		HashTrainSampleRate:         opt.HashTrainSampleRate,
+		M:                           int(opt.M),
+		EfConstruction:              int(opt.EfConstruction),
+	}
+}
+
+func encodeTrainOpt(opt core.TrainOpt) *pb.TrainOpt {
+	return &pb.TrainOpt{
+		TrainType:                   opt.TrainType,
+		Nlist:                       opt.Nlist,
+		BruteThreshold:              opt.BruteThreshold,
+		PartitioningTrainSampleRate: opt.PartitioningTrainSampleRate,
+		HashTrainSampleRate:         opt.HashTrainSampleRate,
+		M:                           int32(opt.M),
+		EfConstruction:              int32(opt.EfConstruction),
+	}
+}
+
+func encodeSearchDebug(debug *core.SearchDebug) *pb.SearchDebug {
+	pbDebug := &pb.SearchDebug{
+		Opt:      encodeQueryOpt(debug.Opt),
+		TookMs:   debug.TookMs,
+		Segments: make([]*pb.SegmentSearchDebug, 0, len(debug.Segments)),
+	}
+	for _, segmentDebug := range debug.Segments {
+		pbDebug.Segments = append(pbDebug.Segments, &pb.SegmentSearchDebug{
+			SegmentId:      segmentDebug.SegmentId,
+			TookMs:         segmentDebug.TookMs,
+			IsBrute:        segmentDebug.IsBrute,
+			Nprobe:         int32(segmentDebug.NProbe),
+			Candidates:     int64(segmentDebug.Candidates),
+			Merged:         int64(segmentDebug.Merged),
+			DroppedDeleted: int64(segmentDebug.DroppedDeleted),
+			Error:          segmentDebug.Error,
+		})
+	}
+	return pbDebug
+}
+
+func encodeEvalResponse(result *core.EvalResponse) *pb.EvalResponse {
+	resp := &pb.EvalResponse{
+		Queries:     int32(result.Queries),
+		Topk:        int32(result.TopK),
+		VectorCount: int64(result.VectorCount),
+		ExactMs:     result.ExactMs,
+		Results:     make([]*pb.EvalResult, 0, len(result.Results)),
+	}
+	for _, evalResult := range result.Results {
+		resp.Results = append(resp.Results, encodeEvalResult(evalResult))
+	}
+	return resp
+}
+
+func encodeEvalResult(result core.EvalResult) *pb.EvalResult {
+	return &pb.EvalResult{
+		Nprobe:        int32(result.NProbe),
+		NprobeRatio:   result.NProbeRatio,
+		Reorder:       int32(result.Reorder),
+		EfSearch:      int32(result.EfSearch),
+		Recall:        result.Recall,
+		LatencyMeanMs: result.LatencyMeanMs,
+		LatencyP99Ms:  result.LatencyP99Ms,
+	}
+}
+
+func encodeSearchTuning(tuning *core.SearchTuning) *pb.SearchTuning {
+	if tuning == nil {
+		return nil
+	}
+	return &pb.SearchTuning{
+		TargetRecall:  tuning.TargetRecall,
+		Topk:          int32(tuning.TopK),
+		Queries:       int32(tuning.Queries),
+		Recall:        tuning.Recall,
+		LatencyMeanMs: tuning.LatencyMeanMs,
+		LatencyP99Ms:  tuning.LatencyP99Ms,
+		TunedAt:       tuning.TunedAt,
+	}
+}
+
+func encodeTuneResponse(result *core.TuneResponse) *pb.TuneResponse {
+	resp := &pb.TuneResponse{
+		Applied:   result.Applied,
+		SearchOpt: encodeQueryOpt(result.SearchOpt),
+		Tuning:    encodeSearchTuning(result.Tuning),
+		Results:   make([]*pb.EvalResult, 0, len(result.Results)),
+	}
+	for _, evalResult := range result.Results {
+		resp.Results = append(resp.Results, encodeEvalResult(evalResult))
+	}
+	return resp
+}
+
+func encodeDocumentMetadata(documents []core.DocumentMetadataResult) *pb.GetDocumentMetadataResponse {
+	resp := &pb.GetDocumentMetadataResponse{Documents: make([]*pb.DocumentMetadata, 0, len(documents))}
+	for _, document := range documents {
+		metadata, _ := json.Marshal(document.Data)
+		resp.Documents = append(resp.Documents, &pb.DocumentMetadata{
+			Vqid:      document.Vqid,
+			Metadata:  metadata,
+			SegmentId: document.SegmentId,
+		})
+	}
+	return resp
+}
+
+func encodeSnapshot(manifest *core.SnapshotManifest) *pb.Snapshot {
+	snapshot := &pb.Snapshot{
+		Version:    uint32(manifest.Version),
+		Name:       manifest.Name,
+		Collection: manifest.Collection,
+		Dim:        uint32(manifest.Dim),
+		IndexType:  manifest.IndexType,
+		Segments:   manifest.Segments,
+		CreatedAt:  manifest.CreatedAt,
+		Files:      make([]*pb.SnapshotFile, 0, len(manifest.Files)),
+	}
+	for _, file := range manifest.Files {
+		snapshot.Files = append(snapshot.Files, &pb.SnapshotFile{
+			Path:   file.Path,
+			Size:   file.Size,
+			Sha256: file.Sha256,
+		})
+	}
+	return snapshot
+}
+
+func encodeCollectionStatistics(stat *core.CollectionStatistics) *pb.CollectionStatistics {
+	loadStatus := stat.LoadStatus
+	pbStat := &pb.CollectionStatistics{
+		CollectionName: stat.CollectionName,
+		IndexType:      stat.IndexType,
+		Aliases:        stat.Aliases,
+		LoadStatus: &pb.CollectionLoadStatus{
+			State:          loadStatus.State,
+			Ready:          loadStatus.Ready,
+			StateChangedAt: loadStatus.StateChangedAt,
+			LoadStartedAt:  loadStatus.LoadStartedAt,
+			LoadedAt:       loadStatus.LoadedAt,
+			LoadDurationMs: loadStatus.LoadDuration,
+			LastError:      loadStatus.LastError,
+			LastErrorAt:    loadStatus.LastErrorAt,
+		},
+		LastAccess:     stat.LastAccess,
+		Dirty:          stat.Dirty,
+		LastFlushAt:    stat.LastFlushAt,
+		LastFlushError: stat.LastFlushError,
+		Segments:       make([]*pb.SegmentStatistics, 0, len(stat.Segments)),
+		SegmentCount:   stat.SegmentCount,
+		TotalIndexSize: stat.TotalIndexSize,
+		VectorCount:    stat.VectorCount,
+		DocCount:       stat.DocCount,
+		MemorySize:     stat.MemorySize,
+		SearchOpt:      encodeQueryOpt(stat.SearchOpt),
+		Tuning:         encodeSearchTuning(stat.Tuning),
+		TrainOpt:       encodeTrainOpt(stat.TrainOpt),
+		Storage:        stat.Storage,
+		Ttl:            stat.TTL,
+	}
+	for _, seg := range stat.Segments {
+		index := seg.IndexStatistics
+		pbStat.Segments = append(pbStat.Segments, &pb.SegmentStatistics{
+			SegmentId: seg.SegmentId,
+			Sealed:    seg.Sealed,
+			Dim:       uint32(seg.Dim),
+			Dirty:     seg.Dirty,
+			IndexStatistics: &pb.IndexStatistics{
+				DatasetSize:    index.DatasetSize,
+				VidSize:        index.VidSize,
+				IndexSize:      index.IndexSize,
+				Nlist:          index.Nlist,
+				VecDim:         index.VecDim,
+				BruteThreshold: index.BruteThreshold,
+				IsBrute:        index.IsBrute,
+				Status:         index.Status,
+				IndexType:      index.IndexType,
+			},
+			VectorCount: seg.VectorCount,
+			DocCount:    seg.DocCount,
+			TrainOpt:    encodeTrainOpt(seg.TrainOpt),
+			TrainDelta:  seg.TrainDelta,
+			TrainDrift:  seg.TrainDrift,
+		})
+	}
+	return pbStat
+}
@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"net"
+	"strings"
+	"vqlite/auth"
+	"vqlite/ratelimit"
+)
+
+// methodClasses is the rate limit class of every limited method, the same as for the HTTP route.
+var methodClasses = map[string]ratelimit.Class{
+	"Statistics":             ratelimit.ClassRead,
+	"GetCollection":          ratelimit.ClassRead,
+	"CreateCollection":       ratelimit.ClassAdmin,
+	"DropCollection":         ratelimit.ClassAdmin,
+	"RenameCollection":       ratelimit.ClassAdmin,
+	"CloneCollection":        ratelimit.ClassAdmin,
+	"TrainCollection":        ratelimit.ClassWrite,
+	"DumpCollection":         ratelimit.ClassAdmin,
+	"DumpCollectionMetadata": ratelimit.ClassAdmin,
+	"DumpCollectionIndex":    ratelimit.ClassAdmin,
+	"LoadCollection":         ratelimit.ClassAdmin,
+	"UnloadCollection":       ratelimit.ClassAdmin,
+	"Search":                 ratelimit.ClassSearch,
+	"EvalCollection":         ratelimit.ClassAdmin,
+	"SetSearchOpt":           ratelimit.ClassAdmin,
+	"SetTrainOpt":            ratelimit.ClassAdmin,
+	"TuneCollection":         ratelimit.ClassAdmin,
+	"AddDocument":            ratelimit.ClassWrite,
+	"BatchAddDocuments":      ratelimit.ClassWrite,
+	"DeleteDocument":         ratelimit.ClassWrite,
+	"UpdateDocumentMetadata": ratelimit.ClassWrite,
+	"GetDocumentMetadata":    ratelimit.ClassRead,
+	"ListSnapshots":          ratelimit.ClassRead,
+	"CreateSnapshot":         ratelimit.ClassAdmin,
+	"RestoreSnapshot":        ratelimit.ClassAdmin,
+	"DeleteSnapshot":         ratelimit.ClassAdmin,
+	"ListAliases":            ratelimit.ClassRead,
+	"GetAlias":               ratelimit.ClassRead,
+	"SetAlias":               ratelimit.ClassAdmin,
+	"DeleteAlias":            ratelimit.ClassAdmin,
+}
+
+// admittedMethods take a slot of the search admission, eval and tune run many searches on the search pool.
+var admittedMethods = map[string]struct{}{
+	"Search":         {},
+	"EvalCollection": {},
+	"TuneCollection": {},
+}
+
+// rateLimitInterceptor limits the requests of the API key, or the peer IP without auth, like
+// middlewares.RateLimit and middlewares.AdmitSearch. It must come after authInterceptor.
+func rateLimitInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+	class, ok := methodClasses[method]
+	if !ok {
+		return handler(ctx, req)
+	}
+	if err := ratelimit.Allow(class, clientOf(ctx)); err != nil {
+		return nil, err
+	}
+	if _, ok := admittedMethods[method]; ok {
+		release, err := ratelimit.AdmitSearch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	return handler(ctx, req)
+}
+
+func clientOf(ctx context.Context) string {
+	if key := auth.FromContext(ctx); key != nil {
+		return "key:" + key.Name
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return "ip:" + host
+		}
+		return "ip:" + p.Addr.String()
+	}
+	return "ip:"
+}
@@ -0,0 +1,379 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"sort"
+	"vqlite/auth"
+	"vqlite/config"
+	"vqlite/core"
+	vqerrors "vqlite/errors"
+	pb "vqlite/proto"
+)
+
+var grpcCodes = map[vqerrors.Code]codes.Code{
+	vqerrors.CodeNotFound:          codes.NotFound,
+	vqerrors.CodeAlreadyExists:     codes.AlreadyExists,
+	vqerrors.CodeInvalidArgument:   codes.InvalidArgument,
+	vqerrors.CodeNotReady:          codes.Unavailable,
+	vqerrors.CodeResourceExhausted: codes.ResourceExhausted,
+	vqerrors.CodeInternal:          codes.Internal,
+	vqerrors.CodeUnauthenticated:   codes.Unauthenticated,
+	vqerrors.CodePermissionDenied:  codes.PermissionDenied,
+	vqerrors.CodeRateLimited:       codes.ResourceExhausted,
+	vqerrors.CodeTooLarge:          codes.ResourceExhausted,
+	vqerrors.CodeDeadlineExceeded:  codes.DeadlineExceeded,
+	vqerrors.CodeCanceled:          codes.Canceled,
+}
+
+// Server implements the VQLite gRPC service on top of core, like handlers do for the HTTP API.
+type Server struct {
+	pb.UnimplementedVQLiteServer
+}
+
+// NewGRPCServer returns a gRPC server with the VQLite service registered, serving TLS if tlsConfig is not nil.
+func NewGRPCServer(tlsConfig *tls.Config) *grpc.Server {
+	maxMsgSize := config.GlobalConfig.ServiceConfig.GrpcMaxMsgSize * 1024 * 1024
+	opts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(maxMsgSize),
+		grpc.MaxSendMsgSize(maxMsgSize),
+		// errorInterceptor comes first, so it converts the errors of the other interceptors as well
+		grpc.ChainUnaryInterceptor(errorInterceptor, authInterceptor, rateLimitInterceptor),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	srv := grpc.NewServer(opts...)
+	pb.RegisterVQLiteServer(srv, &Server{})
+	return srv
+}
+
+// errorInterceptor returns the errors of core as gRPC status with the code matching the error code.
+func errorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err == nil {
+		return resp, nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return resp, err
+	}
+	code, ok := grpcCodes[vqerrors.CodeOf(err)]
+	if !ok {
+		code = codes.Internal
+	}
+	return nil, status.Error(code, err.Error())
+}
+
+// resolve returns the collection name for a collection or an alias, like middlewares.ResolveAlias.
+func resolve(collectionName string) string {
+	return core.VqliteCollectionList.Resolve(collectionName)
+}
+
+func (s *Server) Ping(ctx context.Context, req *pb.PingRequest) (*pb.PingResponse, error) {
+	return &pb.PingResponse{Status: "ok"}, nil
+}
+
+func (s *Server) Statistics(ctx context.Context, req *pb.StatisticsRequest) (*pb.StatisticsResponse, error) {
+	stat := core.Statistics(auth.FromContext(ctx).Allows)
+	resp := &pb.StatisticsResponse{
+		Collections:     make([]*pb.CollectionStatistics, 0, len(stat.Collections)),
+		CollectionCount: stat.CollectionCount,
+		TotalIndexSize:  stat.TotalIndexSize,
+		TotalDocCount:   stat.DocCount,
+	}
+	for i := range stat.Collections {
+		resp.Collections = append(resp.Collections, encodeCollectionStatistics(&stat.Collections[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) GetCollection(ctx context.Context, req *pb.CollectionRequest) (*pb.CollectionResponse, error) {
+	col, err := core.GetCollection(resolve(req.Collection))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CollectionResponse{Collection: encodeCollectionStatistics(col.Statistics())}, nil
+}
+
+func (s *Server) CreateCollection(ctx context.Context, req *pb.CreateCollectionRequest) (*pb.CollectionResponse, error) {
+	col, err := core.CreateCollection(&core.CreateCollectionRequest{
+		Name:      req.Collection,
+		Dim:       int(req.Dim),
+		Storage:   req.Storage,
+		TTL:       int(req.Ttl),
+		IndexType: req.IndexType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CollectionResponse{Collection: encodeCollectionStatistics(col.Statistics())}, nil
+}
+
+func (s *Server) DropCollection(ctx context.Context, req *pb.CollectionRequest) (*pb.EmptyResponse, error) {
+	if err := core.DropCollection(resolve(req.Collection)); err != nil {
+		return nil, err
+	}
+	return &pb.EmptyResponse{}, nil
+}
+
+func (s *Server) RenameCollection(ctx context.Context, req *pb.RenameCollectionRequest) (*pb.CollectionResponse, error) {
+	col, err := core.RenameCollection(resolve(req.Collection), req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CollectionResponse{Collection: encodeCollectionStatistics(col.Statistics())}, nil
+}
+
+func (s *Server) CloneCollection(ctx context.Context, req *pb.CloneCollectionRequest) (*pb.CollectionResponse, error) {
+	col, err := core.CloneCollection(resolve(req.Collection), req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CollectionResponse{Collection: encodeCollectionStatistics(col.Statistics())}, nil
+}
+
+func (s *Server) TrainCollection(ctx context.Context, req *pb.TrainRequest) (*pb.EmptyResponse, error) {
+	if err := core.TrainCollection(resolve(req.Collection), int(req.Threads), req.IgnoreCheck, decodeTrainOpt(req.Opt)); err != nil {
+		return nil, err
+	}
+	return &pb.EmptyResponse{}, nil
+}
+
+func (s *Server) SetTrainOpt(ctx context.Context, req *pb.SetTrainOptRequest) (*pb.CollectionResponse, error) {
+	col, err := core.SetTrainOpt(resolve(req.Collection), decodeTrainOpt(req.Opt))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CollectionResponse{Collection: encodeCollectionStatistics(col.Statistics())}, nil
+}
+
+func (s *Server) DumpCollection(ctx context.Context, req *pb.CollectionRequest) (*pb.EmptyResponse, error) {
+	if err := core.DumpCollection(resolve(req.Collection)); err != nil {
+		return nil, err
+	}
+	return &pb.EmptyResponse{}, nil
+}
+
+func (s *Server) DumpCollectionMetadata(ctx context.Context, req *pb.CollectionRequest) (*pb.EmptyResponse, error) {
+	if err := core.DumpCollectionMetadata(resolve(req.Collection)); err != nil {
+		return nil, err
+	}
+	return &pb.EmptyResponse{}, nil
+}
+
+func (s *Server) DumpCollectionIndex(ctx context.Context, req *pb.CollectionRequest) (*pb.EmptyResponse, error) {
+	if err := core.DumpCollectionIndex(resolve(req.Collection)); err != nil {
+		return nil, err
+	}
+	return &pb.EmptyResponse{}, nil
+}
+
+func (s *Server) LoadCollection(ctx context.Context, req *pb.CollectionRequest) (*pb.EmptyResponse, error) {
+	if err := core.LoadCollection(resolve(req.Collection)); err != nil {
+		return nil, err
+	}
+	return &pb.EmptyResponse{}, nil
+}
+
+func (s *Server) UnloadCollection(ctx context.Context, req *pb.CollectionRequest) (*pb.EmptyResponse, error) {
+	if err := core.UnloadCollection(resolve(req.Collection)); err != nil {
+		return nil, err
+	}
+	return &pb.EmptyResponse{}, nil
+}
+
+func (s *Server) Search(ctx context.Context, req *pb.SearchRequest) (*pb.SearchResponse, error) {
+	vectors, err := decodeVectors(req.Vectors)
+	if err != nil {
+		return nil, err
+	}
+	opt := decodeQueryOpt(req.Opt)
+	// the search is abandoned when the deadline of the call is exceeded or the client cancels it
+	result, err := core.SearchCollectionFlattened(ctx, resolve(req.Collection), vectors, opt)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSearchResponse(result), nil
+}
+
+func (s *Server) EvalCollection(ctx context.Context, req *pb.EvalRequest) (*pb.EvalResponse, error) {
+	evalReq := &core.EvalRequest{
+		SampleSize: int(req.SampleSize),
+		TopK:       int(req.Topk),
+		NProbes:    make([]int, 0, len(req.Nprobes)),
+		Reorders:   make([]int, 0, len(req.Reorders)),
+		EfSearches: make([]int, 0, len(req.EfSearches)),
+	}
+	if req.Vectors != nil {
+		vectors, err := decodeVectorRows(req.Vectors)
+		if err != nil {
+			return nil, err
+		}
+		evalReq.Vectors = vectors
+	}
+	for _, nprobe := range req.Nprobes {
+		evalReq.NProbes = append(evalReq.NProbes, int(nprobe))
+	}
+	for _, reorder := range req.Reorders {
+		evalReq.Reorders = append(evalReq.Reorders, int(reorder))
+	}
+	for _, efSearch := range req.EfSearches {
+		evalReq.EfSearches = append(evalReq.EfSearches, int(efSearch))
+	}
+	result, err := core.EvalCollection(ctx, resolve(req.Collection), evalReq)
+	if err != nil {
+		return nil, err
+	}
+	return encodeEvalResponse(result), nil
+}
+
+func (s *Server) SetSearchOpt(ctx context.Context, req *pb.SetSearchOptRequest) (*pb.CollectionResponse, error) {
+	col, err := core.SetSearchOpt(resolve(req.Collection), decodeQueryOpt(req.Opt))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CollectionResponse{Collection: encodeCollectionStatistics(col.Statistics())}, nil
+}
+
+func (s *Server) TuneCollection(ctx context.Context, req *pb.TuneRequest) (*pb.TuneResponse, error) {
+	result, err := core.TuneCollection(ctx, resolve(req.Collection), &core.TuneRequest{
+		TargetRecall: req.TargetRecall,
+		SampleSize:   int(req.SampleSize),
+		TopK:         int(req.Topk),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return encodeTuneResponse(result), nil
+}
+
+func (s *Server) AddDocument(ctx context.Context, req *pb.AddDocumentRequest) (*pb.EmptyResponse, error) {
+	doc, err := decodeDocument(req.Document)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.AddDocument(resolve(req.Collection), doc); err != nil {
+		return nil, err
+	}
+	return &pb.EmptyResponse{}, nil
+}
+
+func (s *Server) BatchAddDocuments(ctx context.Context, req *pb.BatchAddDocumentsRequest) (*pb.EmptyResponse, error) {
+	docs := &core.BatchAddDocumentsRequest{Documents: make([]core.AddDocumentRequest, 0, len(req.Documents))}
+	for _, pbDoc := range req.Documents {
+		doc, err := decodeDocument(pbDoc)
+		if err != nil {
+			return nil, err
+		}
+		docs.Documents = append(docs.Documents, *doc)
+	}
+	if err := core.BatchAddDocuments(resolve(req.Collection), docs); err != nil {
+		return nil, err
+	}
+	return &pb.EmptyResponse{}, nil
+}
+
+func (s *Server) DeleteDocument(ctx context.Context, req *pb.DeleteDocumentRequest) (*pb.CountResponse, error) {
+	deletedCount, err := core.DeleteDocument(resolve(req.Collection), req.Vqid)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CountResponse{Count: int64(deletedCount)}, nil
+}
+
+func (s *Server) UpdateDocumentMetadata(ctx context.Context, req *pb.UpdateDocumentMetadataRequest) (*pb.CountResponse, error) {
+	metadata, err := decodeMetadata(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	doc := &core.UpdateDocumentMetadataRequest{Vqid: req.Vqid, Metadata: metadata}
+	updatedCount, err := core.UpdateDocumentMetadata(resolve(req.Collection), doc)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CountResponse{Count: int64(updatedCount)}, nil
+}
+
+func (s *Server) GetDocumentMetadata(ctx context.Context, req *pb.GetDocumentMetadataRequest) (*pb.GetDocumentMetadataResponse, error) {
+	documents, err := core.GetDocumentMetadata(resolve(req.Collection), req.Vqid, req.CheckDuplicate)
+	if err != nil {
+		return nil, err
+	}
+	return encodeDocumentMetadata(documents), nil
+}
+
+func (s *Server) ListSnapshots(ctx context.Context, req *pb.CollectionRequest) (*pb.ListSnapshotsResponse, error) {
+	manifests, err := core.ListCollectionSnapshots(resolve(req.Collection))
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.ListSnapshotsResponse{Snapshots: make([]*pb.Snapshot, 0, len(manifests))}
+	for i := range manifests {
+		resp.Snapshots = append(resp.Snapshots, encodeSnapshot(&manifests[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) CreateSnapshot(ctx context.Context, req *pb.SnapshotRequest) (*pb.Snapshot, error) {
+	manifest, err := core.CreateCollectionSnapshot(resolve(req.Collection), req.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSnapshot(manifest), nil
+}
+
+func (s *Server) RestoreSnapshot(ctx context.Context, req *pb.SnapshotRequest) (*pb.Snapshot, error) {
+	manifest, err := core.RestoreCollectionSnapshot(resolve(req.Collection), req.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSnapshot(manifest), nil
+}
+
+func (s *Server) DeleteSnapshot(ctx context.Context, req *pb.SnapshotRequest) (*pb.EmptyResponse, error) {
+	if err := core.DeleteCollectionSnapshot(resolve(req.Collection), req.Snapshot); err != nil {
+		return nil, err
+	}
+	return &pb.EmptyResponse{}, nil
+}
+
+func (s *Server) ListAliases(ctx context.Context, req *pb.ListAliasesRequest) (*pb.ListAliasesResponse, error) {
+	aliases := core.ListAliases(auth.FromContext(ctx).Allows)
+	resp := &pb.ListAliasesResponse{Aliases: make([]*pb.Alias, 0, len(aliases))}
+	for alias, collectionName := range aliases {
+		resp.Aliases = append(resp.Aliases, &pb.Alias{Alias: alias, Collection: collectionName})
+	}
+	sort.Slice(resp.Aliases, func(i, j int) bool {
+		return resp.Aliases[i].Alias < resp.Aliases[j].Alias
+	})
+	return resp, nil
+}
+
+func (s *Server) GetAlias(ctx context.Context, req *pb.AliasRequest) (*pb.Alias, error) {
+	collectionName, err := core.GetAlias(req.Alias)
+	if err == nil && !auth.FromContext(ctx).Allows(collectionName) {
+		err = vqerrors.NotFound("alias [%s] not exists", req.Alias)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Alias{Alias: req.Alias, Collection: collectionName}, nil
+}
+
+func (s *Server) SetAlias(ctx context.Context, req *pb.SetAliasRequest) (*pb.Alias, error) {
+	if err := core.SetAlias(req.Alias, req.Collection); err != nil {
+		return nil, err
+	}
+	return &pb.Alias{Alias: req.Alias, Collection: req.Collection}, nil
+}
+
+func (s *Server) DeleteAlias(ctx context.Context, req *pb.AliasRequest) (*pb.EmptyResponse, error) {
+	if err := core.DeleteAlias(req.Alias); err != nil {
+		return nil, err
+	}
+	return &pb.EmptyResponse{}, nil
+}
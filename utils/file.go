@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"fmt"
 	"github.com/rs/zerolog/log"
 	"io"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 )
 
 // Exists reports whether the named file or directory exists.
@@ -145,16 +147,17 @@ func RemoveIndex(s []os.DirEntry, index int) []os.DirEntry {
 	return append(s[:index], s[index+1:]...)
 }
 
-func FilterValidSegmentDirs(segmentsDirs []os.DirEntry) {
-	for i := 0; i < len(segmentsDirs); i++ {
-		if !segmentsDirs[i].IsDir() {
-			RemoveIndex(segmentsDirs, i)
-		}
-		sParts := strings.Split(segmentsDirs[i].Name(), "_")
-		if len(sParts) != 2 {
-			RemoveIndex(segmentsDirs, i)
+// FilterValidSegmentDirs returns the segment dirs of segmentsDirs, files such as the collection
+// config are left out.
+func FilterValidSegmentDirs(segmentsDirs []os.DirEntry) []os.DirEntry {
+	validDirs := segmentsDirs[:0]
+	for _, segmentDir := range segmentsDirs {
+		if !segmentDir.IsDir() || len(strings.Split(segmentDir.Name(), "_")) != 2 {
+			continue
 		}
+		validDirs = append(validDirs, segmentDir)
 	}
+	return validDirs
 }
 func SortFileNameAscend(segmentsDirs []os.DirEntry) {
 
@@ -169,3 +172,81 @@ func SortFileNameAscend(segmentsDirs []os.DirEntry) {
 		return a < b
 	})
 }
+
+// CopyFile copy src to dst, dst will be created or truncated
+func CopyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := CreatNestedFile(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// LinkOrCopyFile hard link src to dst, copy it if hard link is not supported
+func LinkOrCopyFile(src, dst string) error {
+	if !CreateDirPath(filepath.Dir(dst)) {
+		return fmt.Errorf("cannot create dir %s", filepath.Dir(dst))
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return CopyFile(src, dst)
+}
+
+// LinkOrCopyDir hard link (or copy) all files of src dir to dst dir, keeping the dir structure
+func LinkOrCopyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if !CreateDirPath(filepath.Join(dst, relPath)) {
+				return fmt.Errorf("cannot create dir %s", filepath.Join(dst, relPath))
+			}
+			return nil
+		}
+		return LinkOrCopyFile(path, filepath.Join(dst, relPath))
+	})
+}
+
+// BreakHardLinks replace every file in dir which has more than one hard link with its own copy,
+// so writing the file in place does not change the other links, and returns the number of copied files
+func BreakHardLinks(dir string) (int, error) {
+	count := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok || uint64(stat.Nlink) <= 1 {
+			return nil
+		}
+		tempPath := path + ".unlink"
+		if err := CopyFile(path, tempPath); err != nil {
+			return err
+		}
+		if err := os.Rename(tempPath, path); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
@@ -10,14 +10,25 @@ func init() {
 	gob.Register([]interface{}{})
 }
 
+// Dump encodes v to a temp file and renames it to filename, so the old file
+// is never truncated in place (it may be hard-linked by a snapshot).
 func Dump(v interface{}, filename string) error {
-	file, err := os.Create(filename)
+	tempFilename := filename + ".tmp"
+	file, err := os.Create(tempFilename)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 	encoder := gob.NewEncoder(file)
-	return encoder.Encode(v)
+	if err := encoder.Encode(v); err != nil {
+		file.Close()
+		os.Remove(tempFilename)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tempFilename)
+		return err
+	}
+	return os.Rename(tempFilename, filename)
 }
 
 func Load(v interface{}, filename string) error {